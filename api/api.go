@@ -2,7 +2,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -11,23 +13,32 @@ import (
 	"github.com/Potat-Industries/potat-api/common"
 	"github.com/Potat-Industries/potat-api/common/db"
 	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
 	"github.com/Potat-Industries/potat-api/common/utils"
 	"github.com/gorilla/mux"
 )
 
 // Route represents a single API route with its handler, path, method, and authentication requirement.
+// Routes with a MinLevel above USER or any Scopes always require authentication.
+// RateLimit adds a route specific limit on top of the server wide one, and can itself be
+// overridden in config as "api.<METHOD> <path>".
 type Route struct {
-	Handler http.HandlerFunc
-	Path    string
-	Method  string
-	UseAuth bool
+	Handler   http.HandlerFunc
+	RateLimit *common.RateLimitPolicy
+	Path      string
+	Method    string
+	Scopes    []string
+	UseAuth   bool
+	MinLevel  common.PermissionLevel
 }
 
 // Server represents the API server, including the main router and an authenticated sub-router.
 type Server struct {
-	server       *http.Server
-	router       *mux.Router
-	authedRouter *mux.Router
+	server        *http.Server
+	router        *mux.Router
+	authedRouter  *mux.Router
+	authenticator *middleware.Authenticator
+	limiter       *middleware.RateLimiter
 }
 
 type register struct {
@@ -39,10 +50,12 @@ var registry = &register{} //nolint:gochecknoglobals // Used to conveniently reg
 
 // StartServing initializes and starts the API server with the configured routes and middleware.
 func StartServing(
+	ctx context.Context,
 	config common.Config,
 	postgres *db.PostgresClient,
 	redis *db.RedisClient,
 	clickhouse *db.ClickhouseClient,
+	nats *utils.NatsClient,
 	metrics *utils.Metrics,
 ) error {
 	if config.API.Host == "" || config.API.Port == "" {
@@ -55,11 +68,21 @@ func StartServing(
 
 	api.router.Use(middleware.LogRequest(metrics))
 	api.router.Use(middleware.InjectDatabases(postgres, redis, clickhouse))
-	api.router.Use(middleware.NewRateLimiter(100, 1*time.Minute, redis))
+	api.router.Use(middleware.InjectBroker(nats))
 
-	authenticator := middleware.NewAuthenticator(config.Twitch.ClientSecret, GenericResponse)
+	store, err := storage.New(config.Storage)
+	if err != nil {
+		return fmt.Errorf("failed initializing upload storage: %w", err)
+	}
+	api.router.Use(middleware.InjectStorage(store))
+
+	api.authenticator = middleware.NewAuthenticator(config.Twitch.ClientSecret, GenericResponse)
+	api.router.Use(api.authenticator.SetOptionalAuthMiddleware())
+
+	api.limiter = middleware.NewRateLimiter("api", config.RateLimits, redis)
+	api.router.Use(api.limiter.Policy("default", common.RateLimitPolicy{Limit: 100, Window: 60}))
 	api.authedRouter = api.router.PathPrefix("/").Subrouter()
-	api.authedRouter.Use(authenticator.SetDynamicAuthMiddleware())
+	api.authedRouter.Use(api.authenticator.SetDynamicAuthMiddleware())
 
 	api.server = &http.Server{
 		Handler:      api.router,
@@ -69,6 +92,8 @@ func StartServing(
 		IdleTimeout:  60 * time.Second,
 	}
 
+	migrate(ctx, postgres)
+
 	logger.Info.Printf("API listening on %s", api.server.Addr)
 
 	for _, route := range registry.routes {
@@ -93,12 +118,19 @@ func SetRoute(route Route) {
 }
 
 func (a *Server) registerRoute(route Route) {
-	if route.UseAuth {
-		a.authedRouter.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+	var handler http.Handler = route.Handler
+	if route.RateLimit != nil {
+		handler = a.limiter.Policy(route.Method+" "+route.Path, *route.RateLimit)(handler)
+	}
+
+	if route.UseAuth || route.MinLevel > common.USER || len(route.Scopes) > 0 {
+		minLevel := max(route.MinLevel, common.USER)
+		handler = a.authenticator.RequirePermission(minLevel, route.Scopes)(handler)
+		a.authedRouter.Handle(route.Path, handler).Methods(route.Method)
 
 		return
 	}
-	a.router.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+	a.router.Handle(route.Path, handler).Methods(route.Method)
 }
 
 // GenericResponse is a utility function to send a JSON response with a specified status code and duration.
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// MaxAPIKeys is the maximum number of API keys a single user may hold.
+const MaxAPIKeys = 25
+
+// Scopes that can be granted to API keys. Keys created without scopes are unrestricted.
+//
+//nolint:revive
+const (
+	ScopeChannelsRead   = "channels:read"
+	ScopeChannelsWrite  = "channels:write"
+	ScopeKeysManage     = "keys:manage"
+	ScopeRedirectsRead  = "redirects:read"
+	ScopeRedirectsWrite = "redirects:write"
+	ScopeUploadsRead    = "uploads:read"
+	ScopeUploadsWrite   = "uploads:write"
+)
+
+// APIKeyScopes lists every scope an API key may be granted.
+func APIKeyScopes() []string {
+	return []string{
+		ScopeChannelsRead,
+		ScopeChannelsWrite,
+		ScopeKeysManage,
+		ScopeRedirectsRead,
+		ScopeRedirectsWrite,
+		ScopeUploadsRead,
+		ScopeUploadsWrite,
+	}
+}
+
+// ValidateAPIKey checks the user supplied fields of a new API key.
+func ValidateAPIKey(key common.APIKey) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+
+	if key.Name == "" || len(key.Name) > 64 {
+		errs = append(errs, common.ErrorMessage{Message: "Name must be between 1 and 64 characters"})
+	}
+
+	known := APIKeyScopes()
+	for _, scope := range key.Scopes {
+		if !slices.Contains(known, scope) {
+			errs = append(errs, common.ErrorMessage{Message: fmt.Sprintf("Unknown scope %q", scope)})
+		}
+	}
+
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(time.Now()) {
+		errs = append(errs, common.ErrorMessage{Message: "Expiry must be in the future"})
+	}
+
+	return errs
+}
+
+// CheckAPIKeyGrant checks that the credentials creating a key may grant it. Keys can only be created
+// with scopes the creating key holds, and unrestricted keys can only be created from a session.
+// A nil caller means the request was authenticated with a session.
+func CheckAPIKeyGrant(key common.APIKey, caller *common.APIKey) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+	if caller == nil {
+		return errs
+	}
+
+	if len(key.Scopes) == 0 {
+		errs = append(errs, common.ErrorMessage{Message: "Unrestricted API keys can only be created from a session"})
+
+		return errs
+	}
+
+	if len(caller.Scopes) == 0 {
+		return errs
+	}
+
+	for _, scope := range key.Scopes {
+		if !slices.Contains(caller.Scopes, scope) {
+			errs = append(errs, common.ErrorMessage{Message: fmt.Sprintf("Can not grant scope %q you do not hold", scope)})
+		}
+	}
+
+	return errs
+}
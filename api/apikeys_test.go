@@ -0,0 +1,36 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+func TestAPIKeys__CheckAPIKeyGrant(t *testing.T) {
+	limited := &common.APIKey{Scopes: []string{ScopeKeysManage, ScopeRedirectsRead}}
+	unrestricted := &common.APIKey{Scopes: []string{}}
+
+	tests := []struct {
+		name    string
+		caller  *common.APIKey
+		scopes  []string
+		wantErr bool
+	}{
+		{"session creates unrestricted key", nil, []string{}, false},
+		{"session creates scoped key", nil, []string{ScopeUploadsWrite}, false},
+		{"limited key creates unrestricted key", limited, []string{}, true},
+		{"unrestricted key creates unrestricted key", unrestricted, []string{}, true},
+		{"limited key grants held scope", limited, []string{ScopeRedirectsRead}, false},
+		{"limited key grants unheld scope", limited, []string{ScopeRedirectsRead, ScopeChannelsWrite}, true},
+		{"unrestricted key grants any scope", unrestricted, []string{ScopeChannelsWrite}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := CheckAPIKeyGrant(common.APIKey{Name: "test", Scopes: tc.scopes}, tc.caller)
+			if (len(errs) > 0) != tc.wantErr {
+				t.Errorf("Expected error: %v, got %v", tc.wantErr, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// MaxBlockImport is the maximum number of blocks accepted in a single bulk import.
+const MaxBlockImport = 1000
+
+// BlockAction describes the mutation announced by a ChannelBlockEvent.
+type BlockAction string
+
+//nolint:revive
+const (
+	BlocksAdded   BlockAction = "add"
+	BlocksRemoved BlockAction = "remove"
+)
+
+// ChannelBlockEvent is published on NATS whenever blocks are changed through the API.
+type ChannelBlockEvent struct {
+	ChannelID string           `json:"channel_id"`
+	Platform  common.Platforms `json:"platform"`
+	Action    BlockAction      `json:"action"`
+	Blocks    []common.Block   `json:"blocks"`
+}
+
+// ValidateBlock checks that a block is well formed for its type and that the user is allowed to place it.
+func ValidateBlock(block common.Block, user *common.User) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+
+	if !block.BlockType.IsValid() {
+		return append(errs, common.ErrorMessage{
+			Message: fmt.Sprintf("Invalid block type %q", block.BlockType),
+		})
+	}
+
+	switch block.BlockType {
+	case common.UserBlock, common.GlobalBlock:
+		if block.BlockedUserID <= 0 {
+			errs = append(errs, common.ErrorMessage{Message: "blocked_user_id is required"})
+		}
+	case common.CommandBlock:
+		if strings.TrimSpace(block.CommandName) == "" {
+			errs = append(errs, common.ErrorMessage{Message: "command_name is required for command blocks"})
+		}
+	}
+
+	if block.BlockType == common.GlobalBlock &&
+		(user == nil || common.PermissionLevel(user.Level) < common.ADMIN) { //nolint:gosec
+		errs = append(errs, common.ErrorMessage{Message: "Global blocks require admin permissions"})
+	}
+
+	return errs
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/utils"
+	"github.com/gorilla/mux"
+)
+
+// ErrInvalidPlatform is returned when a route is called with an unknown platform.
+var ErrInvalidPlatform = errors.New("invalid platform")
+
+// CanManageChannel reports whether a user may modify a channel, which is limited to
+// the broadcaster, the channel's listed editors, and admins.
+func CanManageChannel(user *common.User, channel *common.Channel) bool {
+	if user == nil || channel == nil {
+		return false
+	}
+
+	if common.PermissionLevel(user.Level) >= common.ADMIN { //nolint:gosec
+		return true
+	}
+
+	for _, connection := range user.Connections {
+		if connection.Platform != channel.Platform {
+			continue
+		}
+
+		if connection.UserID == channel.ChannelID || slices.Contains(channel.Editors, connection.UserID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetChannelFromRequest resolves the channel addressed by the {platform} and {id} route variables.
+func GetChannelFromRequest(request *http.Request) (*common.Channel, error) {
+	vars := mux.Vars(request)
+
+	platform := common.Platforms(strings.ToUpper(vars["platform"]))
+	if !platform.IsValid() {
+		return nil, ErrInvalidPlatform
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return nil, middleware.ErrMissingContext
+	}
+
+	return postgres.GetChannelByID(request.Context(), vars["id"], platform)
+}
+
+// LoadManagedChannel resolves the channel addressed by the request and checks that the authenticated
+// user may manage it, writing an error response and returning false otherwise.
+func LoadManagedChannel(
+	writer http.ResponseWriter,
+	request *http.Request,
+	start time.Time,
+) (*common.Channel, bool) {
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		GenericResponse(writer, http.StatusUnauthorized, common.GenericResponse[any]{
+			Data:   &[]any{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return nil, false
+	}
+
+	channel, err := GetChannelFromRequest(request)
+	if err != nil {
+		status, message := ChannelLookupStatus(err)
+		GenericResponse(writer, status, common.GenericResponse[any]{
+			Data:   &[]any{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return nil, false
+	}
+
+	if !CanManageChannel(user, channel) {
+		GenericResponse(writer, http.StatusForbidden, common.GenericResponse[any]{
+			Data:   &[]any{},
+			Errors: &[]common.ErrorMessage{{Message: "You are not allowed to manage this channel"}},
+		}, start)
+
+		return nil, false
+	}
+
+	return channel, true
+}
+
+// ChannelLookupStatus maps an error from GetChannelFromRequest to a response status and message.
+func ChannelLookupStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrInvalidPlatform):
+		return http.StatusBadRequest, "Invalid platform"
+	case errors.Is(err, db.ErrPostgresNoRows):
+		return http.StatusNotFound, "Channel not found"
+	default:
+		logger.Error.Printf("Error fetching channel: %v", err)
+
+		return http.StatusInternalServerError, "Error fetching channel"
+	}
+}
+
+// PublishEvent publishes a JSON payload on NATS so the bot can pick up changes made through the API.
+func PublishEvent(ctx context.Context, topic string, payload interface{}) {
+	nats, ok := ctx.Value(middleware.NatsKey).(*utils.NatsClient)
+	if !ok || nats == nil {
+		logger.Warn.Printf("NATS client not found in context, dropping %s event", topic)
+
+		return
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error.Printf("Failed to marshal %s event: %v", topic, err)
+
+		return
+	}
+
+	if err = nats.Publish(topic, data); err != nil {
+		logger.Error.Printf("Failed to publish %s event: %v", topic, err)
+	}
+}
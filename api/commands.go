@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+const (
+	maxTriggerLength     = 100
+	maxResponseLength    = 500
+	maxCommandNameLength = 25
+	maxCommandCooldown   = 86400 // One day in seconds
+	maxCommandDelay      = 60
+	maxCommandUserIDs    = 500
+)
+
+// CommandAction describes the mutation announced by a ChannelCommandEvent.
+type CommandAction string
+
+//nolint:revive
+const (
+	CommandCreated   CommandAction = "create"
+	CommandUpdated   CommandAction = "update"
+	CommandDeleted   CommandAction = "delete"
+	CommandReordered CommandAction = "reorder"
+)
+
+// ChannelCommandEvent is published on NATS whenever a custom command is changed through the API.
+type ChannelCommandEvent struct {
+	Command   *common.ChannelCommand `json:"command,omitempty"`
+	ChannelID string                 `json:"channel_id"`
+	Platform  common.Platforms       `json:"platform"`
+	Action    CommandAction          `json:"action"`
+	Order     []int                  `json:"order,omitempty"`
+}
+
+// NewChannelCommand returns a command with the defaults used when fields are omitted on creation.
+func NewChannelCommand(channel *common.Channel, userID int) common.ChannelCommand {
+	return common.ChannelCommand{
+		ChannelID:      channel.ChannelID,
+		Platform:       string(channel.Platform),
+		UserID:         userID,
+		UserTriggerIDs: []string{},
+		UserIgnoreIDs:  []string{},
+		Active:         true,
+		ActiveOnline:   true,
+		ActiveOffline:  true,
+	}
+}
+
+// ValidateChannelCommand checks the fields of a custom command, and that its trigger
+// and name do not conflict with any other command in the channel.
+func ValidateChannelCommand( //nolint:cyclop
+	command common.ChannelCommand,
+	existing []common.ChannelCommand,
+) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, common.ErrorMessage{Message: fmt.Sprintf(format, args...)})
+	}
+
+	trigger := strings.TrimSpace(command.Trigger)
+	switch {
+	case trigger == "":
+		addErr("Trigger must not be empty")
+	case utf8.RuneCountInString(trigger) > maxTriggerLength:
+		addErr("Trigger must be at most %d characters", maxTriggerLength)
+	}
+
+	if command.Name != nil {
+		name := *command.Name
+		if name == "" || utf8.RuneCountInString(name) > maxCommandNameLength {
+			addErr("Name must be between 1 and %d characters", maxCommandNameLength)
+		}
+		if strings.ContainsFunc(name, unicode.IsSpace) {
+			addErr("Name must not contain whitespace")
+		}
+	}
+
+	hasRunCommand := command.RunCommand != nil && *command.RunCommand != ""
+	if strings.TrimSpace(command.Response) == "" && !hasRunCommand {
+		addErr("Either response or run_command must be set")
+	}
+	if utf8.RuneCountInString(command.Response) > maxResponseLength {
+		addErr("Response must be at most %d characters", maxResponseLength)
+	}
+
+	if command.Cooldown < 0 || command.Cooldown > maxCommandCooldown {
+		addErr("Cooldown must be between 0 and %d", maxCommandCooldown)
+	}
+	if command.Delay < 0 || command.Delay > maxCommandDelay {
+		addErr("Delay must be between 0 and %d", maxCommandDelay)
+	}
+
+	modes := 0
+	for _, enabled := range []bool{command.Reply, command.Whisper, command.Announce} {
+		if enabled {
+			modes++
+		}
+	}
+	if modes > 1 {
+		addErr("Only one of reply, whisper or announce may be enabled")
+	}
+
+	if !command.ActiveOnline && !command.ActiveOffline {
+		addErr("Command must be active while online, offline, or both")
+	}
+
+	if len(command.UserTriggerIDs) > maxCommandUserIDs || len(command.UserIgnoreIDs) > maxCommandUserIDs {
+		addErr("User trigger and ignore lists must contain at most %d users", maxCommandUserIDs)
+	}
+	for _, id := range command.UserTriggerIDs {
+		if slices.Contains(command.UserIgnoreIDs, id) {
+			addErr("User %s cannot be both a trigger and ignored user", id)
+		}
+	}
+
+	for _, other := range existing {
+		if other.CommandID == command.CommandID {
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(other.Trigger), trigger) {
+			addErr("Trigger conflicts with command %d", other.CommandID)
+		}
+		if command.Name != nil && other.Name != nil && strings.EqualFold(*other.Name, *command.Name) {
+			addErr("Name conflicts with command %d", other.CommandID)
+		}
+	}
+
+	return errs
+}
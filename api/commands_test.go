@@ -0,0 +1,41 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+func TestCommands__ValidateChannelCommand(t *testing.T) {
+	channel := &common.Channel{ChannelID: "123", Platform: common.TWITCH}
+	existing := []common.ChannelCommand{{CommandID: 1, Trigger: "!hello", Response: "hi"}}
+
+	tests := []struct {
+		name    string
+		mutate  func(c *common.ChannelCommand)
+		wantErr bool
+	}{
+		{"valid", func(_ *common.ChannelCommand) {}, false},
+		{"empty trigger", func(c *common.ChannelCommand) { c.Trigger = " " }, true},
+		{"conflicting trigger", func(c *common.ChannelCommand) { c.Trigger = "!HELLO" }, true},
+		{"own trigger on update", func(c *common.ChannelCommand) { c.CommandID = 1; c.Trigger = "!hello" }, false},
+		{"no response", func(c *common.ChannelCommand) { c.Response = "" }, true},
+		{"negative cooldown", func(c *common.ChannelCommand) { c.Cooldown = -1 }, true},
+		{"reply and whisper", func(c *common.ChannelCommand) { c.Reply = true; c.Whisper = true }, true},
+		{"never active", func(c *common.ChannelCommand) { c.ActiveOnline = false; c.ActiveOffline = false }, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			command := NewChannelCommand(channel, 1)
+			command.Trigger = "!bye"
+			command.Response = "bye"
+			tc.mutate(&command)
+
+			errs := ValidateChannelCommand(command, existing)
+			if (len(errs) > 0) != tc.wantErr {
+				t.Errorf("Expected error: %v, got %v", tc.wantErr, errs)
+			}
+		})
+	}
+}
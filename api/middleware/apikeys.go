@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/jackc/pgx/v5"
+)
+
+// AuthedKey is the key for the API key a request was authenticated with in the request context.
+const AuthedKey = AuthenticatedUser("authenticated-key")
+
+const (
+	apiKeyScheme      = "Key "
+	apiKeyTokenPrefix = "potat_"
+	apiKeyBytes       = 32
+	apiKeyPrefixLen   = len(apiKeyTokenPrefix) + 8
+)
+
+// GenerateAPIKey creates a new random API key, returning the key, its displayable prefix and its hash.
+func GenerateAPIKey() (string, string, string, error) {
+	buf := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+
+	key := apiKeyTokenPrefix + hex.EncodeToString(buf)
+
+	return key, key[:apiKeyPrefixLen], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func isAPIKey(header string) bool {
+	return strings.HasPrefix(header, apiKeyScheme)
+}
+
+func (a *Authenticator) verifyAPIKey(ctx context.Context, header string) (bool, *common.User, *common.APIKey) {
+	postgres, ok := ctx.Value(PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return false, &common.User{}, nil
+	}
+
+	key, err := postgres.UseAPIKey(ctx, HashAPIKey(strings.TrimPrefix(header, apiKeyScheme)))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Error.Println("Error verifying API key: ", err)
+		}
+
+		return false, &common.User{}, nil
+	}
+
+	user, err := a.loadUser(ctx, key.UserID)
+	if err != nil {
+		logger.Warn.Println("Error fetching API key owner: ", err)
+
+		return false, &common.User{}, nil
+	}
+
+	return true, user, key
+}
+
+func withIdentity(ctx context.Context, user *common.User, key *common.APIKey) context.Context {
+	ctx = context.WithValue(ctx, AuthedUser, user)
+	if key == nil {
+		return ctx
+	}
+
+	ctx = context.WithValue(ctx, AuthedKey, key)
+	if len(key.Scopes) > 0 {
+		ctx = context.WithValue(ctx, AuthedScopes, key.Scopes)
+	}
+
+	return ctx
+}
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/Potat-Industries/potat-api/common"
-	"github.com/Potat-Industries/potat-api/common/db"
 	"github.com/Potat-Industries/potat-api/common/logger"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -28,7 +27,10 @@ type AuthenticatedUser string
 
 type potatClaims struct {
 	jwt.RegisteredClaims
-	UserID int `json:"user_id"`
+	TokenType  tokenType `json:"typ"`
+	SessionID  string    `json:"sid,omitempty"`
+	UserID     int       `json:"user_id"`
+	IssuedAtMs int64     `json:"iat_ms,omitempty"`
 }
 
 type unauthFunc func(
@@ -57,7 +59,7 @@ func NewAuthenticator(secret string, unauthorizedFunc unauthFunc) *Authenticator
 
 func (a *Authenticator) sendUnauthorized(w http.ResponseWriter) {
 	logger.Warn.Println("Unauthorized request")
-	a.unauthorizedFunc(w, http.StatusTeapot, unauthorizedResponse{
+	a.unauthorizedFunc(w, http.StatusUnauthorized, unauthorizedResponse{
 		Data:   &[]string{},
 		Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
 	}, time.Now())
@@ -78,6 +80,26 @@ func (a *Authenticator) SetStaticAuthMiddleware() func(http.Handler) http.Handle
 	}
 }
 
+// SetStaticOrDynamicAuthMiddleware returns a middleware that lets requests with the static auth key of
+// another authenticator through anonymously, and otherwise verifies the provided dynamic auth token.
+func (a *Authenticator) SetStaticOrDynamicAuthMiddleware(static *Authenticator) func(http.Handler) http.Handler {
+	dynamic := a.SetDynamicAuthMiddleware()
+
+	return func(next http.Handler) http.Handler {
+		authed := dynamic(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := strings.Replace(r.Header.Get("Authorization"), "Bearer ", "", 1)
+			if static.verifySimpleAuthKey(auth) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+			authed.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (a *Authenticator) verifySimpleAuthKey(provided string) bool {
 	return subtle.ConstantTimeCompare([]byte(provided), a.secret) == 1
 }
@@ -86,6 +108,12 @@ func (a *Authenticator) verifySimpleAuthKey(provided string) bool {
 func (a *Authenticator) SetDynamicAuthMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if user, ok := request.Context().Value(AuthedUser).(*common.User); ok && user != nil {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
 			token := request.Header.Get("Authorization")
 			if token == "" {
 				a.sendUnauthorized(writer)
@@ -93,41 +121,54 @@ func (a *Authenticator) SetDynamicAuthMiddleware() func(http.Handler) http.Handl
 				return
 			}
 
-			ok, user := a.verifyDynamicAuth(request.Context(), token)
+			ok, user, key := a.verifyDynamicAuth(request.Context(), token)
 			if !ok {
 				a.sendUnauthorized(writer)
 
 				return
 			}
 
-			ctx := context.WithValue(request.Context(), AuthedUser, user)
-			next.ServeHTTP(writer, request.WithContext(ctx))
+			if isBlacklisted(user) {
+				a.sendForbidden(writer, "You are blacklisted")
+
+				return
+			}
+
+			next.ServeHTTP(writer, request.WithContext(withIdentity(request.Context(), user, key)))
 		})
 	}
 }
 
-func (a *Authenticator) verifyDynamicAuth(ctx context.Context, token string) (bool, *common.User) {
+func (a *Authenticator) verifyDynamicAuth(
+	ctx context.Context,
+	token string,
+) (bool, *common.User, *common.APIKey) {
+	if isAPIKey(token) {
+		return a.verifyAPIKey(ctx, token)
+	}
+
 	token = strings.Replace(token, "Bearer ", "", 1)
 	claims, err := a.verifyJWT(token)
-	if err != nil {
-		return false, &common.User{}
+	if err != nil || claims.TokenType != accessToken {
+		return false, &common.User{}, nil
 	}
 
-	postgres, ok := ctx.Value(PostgresKey).(*db.PostgresClient)
-	if !ok {
-		logger.Error.Println("Postgres client not found in context")
+	if err = a.checkRevoked(ctx, claims); err != nil {
+		if !errors.Is(err, errRevokedToken) {
+			logger.Error.Println("Error checking token revocation: ", err)
+		}
 
-		return false, &common.User{}
+		return false, &common.User{}, nil
 	}
 
-	user, err := postgres.GetUserByInternalID(ctx, claims.UserID)
+	user, err := a.loadUser(ctx, claims.UserID)
 	if err != nil {
 		logger.Warn.Println("Error fetching authenticated user: ", err)
 
-		return false, &common.User{}
+		return false, &common.User{}, nil
 	}
 
-	return true, user
+	return true, user, nil
 }
 
 func (a *Authenticator) jwtKeyFunc(token *jwt.Token) (interface{}, error) {
@@ -139,33 +180,22 @@ func (a *Authenticator) jwtKeyFunc(token *jwt.Token) (interface{}, error) {
 }
 
 func (a *Authenticator) verifyJWT(tokenString string) (*potatClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &potatClaims{}, a.jwtKeyFunc)
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		&potatClaims{},
+		a.jwtKeyFunc,
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithAudience(jwtAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*potatClaims); ok && token.Valid {
+	if claims, ok := token.Claims.(*potatClaims); ok && token.Valid && claims.ID != "" {
 		return claims, nil
 	}
 
 	return nil, errInvalidToken
 }
-
-// CreateJWT creates a new JWT token for the provided user ID.
-func (a *Authenticator) CreateJWT(userID int) (string, error) {
-	claims := potatClaims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(183 * 24 * time.Hour)),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	signedToken, err := token.SignedString(a.secret)
-	if err != nil {
-		return "", err
-	}
-
-	return signedToken, nil
-}
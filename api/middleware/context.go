@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/storage"
+	"github.com/Potat-Industries/potat-api/common/utils"
 )
 
 // ErrMissingContext is returned when a database client is not found in the request context.
@@ -18,6 +20,8 @@ const (
 	PostgresKey   contextKey = "postgres"
 	RedisKey      contextKey = "redis"
 	ClickhouseKey contextKey = "clickhouse"
+	NatsKey       contextKey = "nats"
+	StorageKey    contextKey = "storage"
 )
 
 // InjectDatabases returns a middleware that injects DB clients into the request context.
@@ -36,3 +40,25 @@ func InjectDatabases(
 		})
 	}
 }
+
+// InjectBroker returns a middleware that injects the NATS client into the request context.
+func InjectBroker(nats *utils.NatsClient) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), NatsKey, nats)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// InjectStorage returns a middleware that injects the upload storage into the request context.
+func InjectStorage(store storage.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), StorageKey, store)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
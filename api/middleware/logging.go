@@ -23,6 +23,11 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.headers = lrw.ResponseWriter.Header()
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
 // LogRequest logs the request method, URI, status code, and duration of the request.
 func LogRequest(metrics *utils.Metrics) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// AuthedScopes is the key for the scopes granted to the current credentials in the request context.
+// Sessions carry no scopes and are treated as unrestricted.
+const AuthedScopes = AuthenticatedUser("authenticated-scopes")
+
+type forbiddenResponse = common.GenericResponse[string]
+
+func (a *Authenticator) sendForbidden(w http.ResponseWriter, message string) {
+	a.unauthorizedFunc(w, http.StatusForbidden, forbiddenResponse{
+		Data:   &[]string{},
+		Errors: &[]common.ErrorMessage{{Message: message}},
+	}, time.Now())
+}
+
+func isBlacklisted(user *common.User) bool {
+	return common.PermissionLevel(user.Level) <= common.BLACKLISTED //nolint:gosec
+}
+
+// SetOptionalAuthMiddleware returns a middleware that resolves the user for requests presenting a token,
+// rejecting blacklisted users. Requests without a valid token are passed through anonymously.
+func (a *Authenticator) SetOptionalAuthMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			token := request.Header.Get("Authorization")
+			if token == "" {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			ok, user, key := a.verifyDynamicAuth(request.Context(), token)
+			if !ok {
+				next.ServeHTTP(writer, request)
+
+				return
+			}
+
+			if isBlacklisted(user) {
+				logger.Warn.Printf("Rejected request from blacklisted user %d", user.ID)
+				a.sendForbidden(writer, "You are blacklisted")
+
+				return
+			}
+
+			next.ServeHTTP(writer, request.WithContext(withIdentity(request.Context(), user, key)))
+		})
+	}
+}
+
+// RequirePermission returns a middleware that rejects authenticated users below the given level,
+// or whose credentials were not granted every listed scope.
+func (a *Authenticator) RequirePermission(
+	level common.PermissionLevel,
+	scopes []string,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			user, ok := request.Context().Value(AuthedUser).(*common.User)
+			if !ok || user == nil {
+				a.sendUnauthorized(writer)
+
+				return
+			}
+
+			if isBlacklisted(user) {
+				a.sendForbidden(writer, "You are blacklisted")
+
+				return
+			}
+
+			if common.PermissionLevel(user.Level) < level { //nolint:gosec
+				a.sendForbidden(writer, "You do not have permission to access this resource")
+
+				return
+			}
+
+			for _, scope := range scopes {
+				if !HasScope(request.Context(), scope) {
+					a.sendForbidden(writer, "Missing required scope: "+scope)
+
+					return
+				}
+			}
+
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// HasScope reports whether the credentials of a request were granted a scope.
+func HasScope(ctx context.Context, scope string) bool {
+	granted, restricted := ctx.Value(AuthedScopes).([]string)
+
+	return !restricted || slices.Contains(granted, scope)
+}
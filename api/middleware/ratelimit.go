@@ -3,26 +3,138 @@ package middleware
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Potat-Industries/potat-api/common"
 	"github.com/Potat-Industries/potat-api/common/db"
 	"github.com/Potat-Industries/potat-api/common/logger"
 )
 
 var errBadRedisResponse = errors.New("invalid result from Redis")
 
-// NewRateLimiter returns a new rate limiter middleware.
-func NewRateLimiter(limit int64, window time.Duration, redis *db.RedisClient) func(http.Handler) http.Handler {
+// Fixed window: INCR a counter that expires with the window.
+const fixedWindowScript = `
+	local current = redis.call("INCR", KEYS[1])
+	if current == 1 then
+		redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	end
+
+	local ttl = redis.call("PTTL", KEYS[1])
+	local allowed = 0
+	if current <= tonumber(ARGV[2]) then
+		allowed = 1
+	end
+
+	return {allowed, tonumber(ARGV[2]) - current, ttl}
+`
+
+// Sliding window: a sorted set of request timestamps, trimmed to the window on every request.
+const slidingWindowScript = `
+	local window = tonumber(ARGV[1])
+	local limit = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+	local count = redis.call("ZCARD", KEYS[1])
+	local allowed = 0
+	if count < limit then
+		redis.call("ZADD", KEYS[1], now, now .. "-" .. ARGV[4])
+		count = count + 1
+		allowed = 1
+	end
+
+	redis.call("PEXPIRE", KEYS[1], window)
+
+	local reset = window
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	if oldest[2] then
+		reset = tonumber(oldest[2]) + window - now
+	end
+
+	return {allowed, limit - count, reset}
+`
+
+// Token bucket: refills limit tokens evenly over the window, bursting up to limit.
+const tokenBucketScript = `
+	local window = tonumber(ARGV[1])
+	local limit = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local rate = limit / window
+
+	local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+	local tokens = tonumber(bucket[1]) or limit
+	local updated = tonumber(bucket[2]) or now
+
+	tokens = math.min(limit, tokens + (now - updated) * rate)
+
+	local allowed = 0
+	if tokens >= 1 then
+		tokens = tokens - 1
+		allowed = 1
+	end
+
+	redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+	redis.call("PEXPIRE", KEYS[1], window)
+
+	local reset = 0
+	if tokens < 1 then
+		reset = math.ceil((1 - tokens) / rate)
+	end
+
+	return {allowed, math.floor(tokens), reset}
+`
+
+// RateLimiter applies rate limit policies to requests, storing its counters under a per-server namespace.
+type RateLimiter struct {
+	redis     *db.RedisClient
+	policies  map[string]common.RateLimitPolicy
+	namespace string
+	trusted   []netip.Prefix
+}
+
+// NewRateLimiter creates a rate limiter for a server. Policies configured as "<namespace>.<name>"
+// override the defaults passed to Policy.
+func NewRateLimiter(namespace string, config common.RateLimitConfig, redis *db.RedisClient) *RateLimiter {
+	limiter := &RateLimiter{
+		redis:     redis,
+		namespace: namespace,
+		policies:  config.Policies,
+		trusted:   make([]netip.Prefix, 0, len(config.TrustedProxies)),
+	}
+
+	for _, cidr := range config.TrustedProxies {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				logger.Warn.Printf("Ignoring invalid trusted proxy %q: %v", cidr, err)
+
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		limiter.trusted = append(limiter.trusted, prefix.Masked())
+	}
+
+	return limiter
+}
+
+// Policy returns a middleware enforcing the named policy, using fallback for anything not configured.
+func (l *RateLimiter) Policy(name string, fallback common.RateLimitPolicy) func(http.Handler) http.Handler {
+	policy := mergePolicy(l.policies[l.namespace+"."+name], fallback)
+	window := time.Duration(policy.Window) * time.Second
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-			ip := request.RemoteAddr
-			if forwardedFor := request.Header.Get("CF-Connecting-IP"); forwardedFor != "" {
-				ip = forwardedFor
-			}
+			key := "ratelimit:" + l.namespace + ":" + name + ":" + l.identify(request, policy.KeyBy)
 
-			allowed, remaining, remainingTTL, err := getIPToken(request.Context(), ip, limit, window, redis)
+			allowed, remaining, reset, err := l.take(request.Context(), key, policy)
 			if err != nil {
 				http.Error(
 					writer,
@@ -33,13 +145,15 @@ func NewRateLimiter(limit int64, window time.Duration, redis *db.RedisClient) fu
 				return
 			}
 
-			writer.Header().Set("X-RateLimit-Reset", strconv.FormatInt(remainingTTL, 10))
-			writer.Header().Set("X-RateLimit-Limit", strconv.FormatInt(limit, 10))
+			resetSeconds := strconv.FormatInt((reset+999)/1000, 10)
+			writer.Header().Set("X-RateLimit-Reset", resetSeconds)
+			writer.Header().Set("X-RateLimit-Limit", strconv.FormatInt(policy.Limit, 10))
 			writer.Header().Set("X-RateLimit-Window", strconv.FormatInt(int64(window.Seconds()), 10))
-			writer.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+			writer.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(max(remaining, 0), 10))
+			writer.Header().Set("X-RateLimit-Policy", string(policy.Algorithm))
 
 			if !allowed {
-				writer.Header().Set("Retry-After", strconv.FormatInt(remainingTTL, 10))
+				writer.Header().Set("Retry-After", resetSeconds)
 
 				http.Error(
 					writer,
@@ -55,35 +169,133 @@ func NewRateLimiter(limit int64, window time.Duration, redis *db.RedisClient) fu
 	}
 }
 
-func getIPToken(
+func mergePolicy(policy, fallback common.RateLimitPolicy) common.RateLimitPolicy {
+	if policy.Algorithm == "" {
+		policy.Algorithm = fallback.Algorithm
+	}
+	if policy.KeyBy == "" {
+		policy.KeyBy = fallback.KeyBy
+	}
+	if policy.Limit <= 0 {
+		policy.Limit = fallback.Limit
+	}
+	if policy.Window <= 0 {
+		policy.Window = fallback.Window
+	}
+
+	if policy.Algorithm == "" {
+		policy.Algorithm = common.SlidingWindow
+	}
+	if policy.KeyBy == "" {
+		policy.KeyBy = common.KeyByIdentity
+	}
+	if policy.Window <= 0 {
+		policy.Window = 60
+	}
+
+	return policy
+}
+
+func (l *RateLimiter) identify(request *http.Request, keyBy common.RateLimitKey) string {
+	ctx := request.Context()
+	key, hasKey := ctx.Value(AuthedKey).(*common.APIKey)
+	user, hasUser := ctx.Value(AuthedUser).(*common.User)
+
+	switch keyBy {
+	case common.KeyByAPIKey:
+		if hasKey && key != nil {
+			return "key:" + strconv.Itoa(key.ID)
+		}
+	case common.KeyByUser:
+		if hasUser && user != nil {
+			return "user:" + strconv.Itoa(user.ID)
+		}
+	case common.KeyByIdentity:
+		if hasKey && key != nil {
+			return "key:" + strconv.Itoa(key.ID)
+		}
+		if hasUser && user != nil {
+			return "user:" + strconv.Itoa(user.ID)
+		}
+	default:
+	}
+
+	return "ip:" + l.ClientIP(request)
+}
+
+// ClientIP returns the address of the client, only trusting forwarded headers set by a trusted proxy.
+func (l *RateLimiter) ClientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+
+	remote, err := netip.ParseAddr(host)
+	if err != nil || !l.isTrusted(remote) {
+		return host
+	}
+
+	if connecting := request.Header.Get("CF-Connecting-IP"); connecting != "" {
+		if addr, err := netip.ParseAddr(strings.TrimSpace(connecting)); err == nil {
+			return addr.String()
+		}
+	}
+
+	// Walk X-Forwarded-For from the right, the first untrusted hop is the client.
+	hops := strings.Split(request.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		if !l.isTrusted(addr) {
+			return addr.String()
+		}
+		host = addr.String()
+	}
+
+	return host
+}
+
+func (l *RateLimiter) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range l.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *RateLimiter) take(
 	ctx context.Context,
-	ip string,
-	limit int64,
-	window time.Duration,
-	redis *db.RedisClient,
+	key string,
+	policy common.RateLimitPolicy,
 ) (bool, int64, int64, error) {
-	// Set key expiry if its first request from an ip
-	luaScript := `
-		local current = redis.call("INCR", KEYS[1])
-		if current == 1 then
-				redis.call("EXPIRE", KEYS[1], ARGV[1])
-		end
-
-		local ttl = redis.call("TTL", KEYS[1])
-		local allowed = 0
-		if current <= tonumber(ARGV[2]) then
-			allowed = 1
-		end
-
-		return {current, allowed, ttl}
-	`
-
-	result, err := redis.Eval(
+	windowMs := int64(policy.Window) * 1000
+	now := time.Now()
+
+	var script string
+	switch policy.Algorithm {
+	case common.TokenBucket:
+		script = tokenBucketScript
+	case common.FixedWindow:
+		script = fixedWindowScript
+	case common.SlidingWindow:
+		script = slidingWindowScript
+	default:
+		script = slidingWindowScript
+	}
+
+	result, err := l.redis.Eval(
 		ctx,
-		luaScript,
-		[]string{ip},
-		int(window.Seconds()),
-		limit,
+		script,
+		[]string{key},
+		windowMs,
+		policy.Limit,
+		now.UnixMilli(),
+		now.UnixNano(),
 	).Result()
 	if err != nil {
 		logger.Error.Println("Error evaluating Lua script", err)
@@ -92,29 +304,24 @@ func getIPToken(
 	}
 
 	results, ok := result.([]interface{})
-	if !ok {
-		return false, 0, 0, errBadRedisResponse
-	}
-	if len(results) != 3 {
+	if !ok || len(results) != 3 {
 		return false, 0, 0, errBadRedisResponse
 	}
 
-	remainder, ok := results[0].(int64)
+	allowed, ok := results[0].(int64)
 	if !ok {
 		return false, 0, 0, errBadRedisResponse
 	}
-	remaining := limit - remainder
 
-	allowedInt, ok := results[1].(int64)
+	remaining, ok := results[1].(int64)
 	if !ok {
 		return false, 0, 0, errBadRedisResponse
 	}
-	allowed := allowedInt == 1
 
-	remainingTTL, ok := results[2].(int64)
+	reset, ok := results[2].(int64)
 	if !ok {
 		return false, 0, 0, errBadRedisResponse
 	}
 
-	return allowed, remaining, remainingTTL, nil
+	return allowed == 1, remaining, reset, nil
 }
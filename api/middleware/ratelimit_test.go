@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+func TestRateLimiter__ClientIP(t *testing.T) {
+	limiter := NewRateLimiter("test", common.RateLimitConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "127.0.0.1"},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		remote    string
+		connectIP string
+		forwarded string
+		expected  string
+	}{
+		{"direct client", "203.0.113.7:5123", "", "", "203.0.113.7"},
+		{"untrusted proxy headers ignored", "203.0.113.7:5123", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"trusted cloudflare header", "10.1.2.3:443", "198.51.100.1", "", "198.51.100.1"},
+		{"trusted forwarded for", "127.0.0.1:80", "", "198.51.100.2, 10.0.0.5", "198.51.100.2"},
+		{"spoofed forwarded chain", "127.0.0.1:80", "", "1.1.1.1, 198.51.100.2", "198.51.100.2"},
+		{"only trusted hops", "10.0.0.1:80", "", "10.0.0.2", "10.0.0.2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.RemoteAddr = tc.remote
+			if tc.connectIP != "" {
+				request.Header.Set("CF-Connecting-IP", tc.connectIP)
+			}
+			if tc.forwarded != "" {
+				request.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+
+			if got := limiter.ClientIP(request); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
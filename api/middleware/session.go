@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+	userCacheTTL    = 5 * time.Minute
+	jwtIssuer       = "potat-api"
+	jwtAudience     = "potatbotat"
+)
+
+var (
+	errRevokedToken   = errors.New("token has been revoked")
+	errWrongTokenType = errors.New("wrong token type")
+)
+
+type tokenType string
+
+const (
+	accessToken  tokenType = "access"
+	refreshToken tokenType = "refresh"
+)
+
+// TokenPair is a short lived access token and the refresh token used to renew it.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func denylistKey(jti string) string {
+	return "session:denylist:" + jti
+}
+
+func revokedBeforeKey(userID int) string {
+	return fmt.Sprintf("session:revoked-before:%d", userID)
+}
+
+func userCacheKey(userID int) string {
+	return fmt.Sprintf("session:user:%d", userID)
+}
+
+func (a *Authenticator) signClaims(claims potatClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(a.secret)
+}
+
+func newClaims(userID int, kind tokenType, sessionID string, ttl time.Duration) potatClaims {
+	now := time.Now()
+
+	return potatClaims{
+		UserID:     userID,
+		TokenType:  kind,
+		SessionID:  sessionID,
+		IssuedAtMs: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+}
+
+// CreateSession issues a new refresh token and an access token bound to it.
+func (a *Authenticator) CreateSession(userID int) (*TokenPair, error) {
+	refresh := newClaims(userID, refreshToken, "", refreshTokenTTL)
+	refresh.SessionID = refresh.ID
+
+	signedRefresh, err := a.signClaims(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	signedAccess, err := a.signClaims(newClaims(userID, accessToken, refresh.ID, accessTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  signedAccess,
+		RefreshToken: signedRefresh,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// RefreshSession exchanges a valid refresh token for a new token pair, revoking the old refresh token.
+func (a *Authenticator) RefreshSession(ctx context.Context, token string) (*TokenPair, error) {
+	claims, err := a.verifyJWT(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != refreshToken {
+		return nil, errWrongTokenType
+	}
+
+	if err = a.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	// Claiming the token is atomic, so replaying it alongside the real client can only win once.
+	if err = a.claim(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return nil, err
+	}
+
+	return a.CreateSession(claims.UserID)
+}
+
+// RevokeSession revokes the session the given token belongs to, including its refresh token.
+func (a *Authenticator) RevokeSession(ctx context.Context, token string) error {
+	claims, err := a.verifyJWT(token)
+	if err != nil {
+		return err
+	}
+
+	if err = a.denylist(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" && claims.SessionID != claims.ID {
+		return a.denylist(ctx, claims.SessionID, time.Now().Add(refreshTokenTTL))
+	}
+
+	return nil
+}
+
+// RevokeAllSessions revokes every token issued to a user up to now. The cutoff is kept in
+// milliseconds so a login right after logging out everywhere is not caught by it.
+func (a *Authenticator) RevokeAllSessions(ctx context.Context, userID int) error {
+	redis, ok := ctx.Value(RedisKey).(*db.RedisClient)
+	if !ok {
+		return ErrMissingContext
+	}
+
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := redis.SetEx(ctx, revokedBeforeKey(userID), now, refreshTokenTTL).Err(); err != nil {
+		return err
+	}
+
+	return redis.Del(ctx, userCacheKey(userID)).Err()
+}
+
+func (a *Authenticator) denylist(ctx context.Context, jti string, expiresAt time.Time) error {
+	redis, ok := ctx.Value(RedisKey).(*db.RedisClient)
+	if !ok {
+		return ErrMissingContext
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return redis.SetEx(ctx, denylistKey(jti), "1", ttl).Err()
+}
+
+// claim denylists a token only if it is not denylisted yet, returning errRevokedToken if it was.
+func (a *Authenticator) claim(ctx context.Context, jti string, expiresAt time.Time) error {
+	redis, ok := ctx.Value(RedisKey).(*db.RedisClient)
+	if !ok {
+		return ErrMissingContext
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return errRevokedToken
+	}
+
+	claimed, err := redis.SetNX(ctx, denylistKey(jti), "1", ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errRevokedToken
+	}
+
+	return nil
+}
+
+func (a *Authenticator) checkRevoked(ctx context.Context, claims *potatClaims) error {
+	redis, ok := ctx.Value(RedisKey).(*db.RedisClient)
+	if !ok {
+		return ErrMissingContext
+	}
+
+	keys := []string{denylistKey(claims.ID)}
+	if claims.SessionID != "" && claims.SessionID != claims.ID {
+		keys = append(keys, denylistKey(claims.SessionID))
+	}
+
+	denied, err := redis.Exists(ctx, keys...).Result()
+	if err != nil {
+		return err
+	}
+	if denied > 0 {
+		return errRevokedToken
+	}
+
+	revokedBefore, err := redis.Get(ctx, revokedBeforeKey(claims.UserID)).Int64()
+	if err != nil && !errors.Is(err, db.ErrRedisNil) {
+		return err
+	}
+
+	if claims.IssuedAt == nil || (revokedBefore > 0 && issuedAtMillis(claims) <= revokedBefore) {
+		return errRevokedToken
+	}
+
+	return nil
+}
+
+// issuedAtMillis returns when a token was issued in milliseconds, falling back to the
+// second precision iat claim for tokens issued without iat_ms.
+func issuedAtMillis(claims *potatClaims) int64 {
+	if claims.IssuedAtMs > 0 {
+		return claims.IssuedAtMs
+	}
+
+	return claims.IssuedAt.UnixMilli()
+}
+
+func (a *Authenticator) loadUser(ctx context.Context, userID int) (*common.User, error) {
+	redis, ok := ctx.Value(RedisKey).(*db.RedisClient)
+	if !ok {
+		return nil, ErrMissingContext
+	}
+
+	postgres, ok := ctx.Value(PostgresKey).(*db.PostgresClient)
+	if !ok {
+		return nil, ErrMissingContext
+	}
+
+	cached, err := redis.Get(ctx, userCacheKey(userID)).Bytes()
+	if err == nil {
+		var user common.User
+		if err = json.Unmarshal(cached, &user); err == nil {
+			return &user, nil
+		}
+	}
+
+	user, err := postgres.GetUserByInternalID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		logger.Warn.Printf("Failed to marshal user %d for cache: %v", userID, err)
+
+		return user, nil
+	}
+
+	if err = redis.SetEx(ctx, userCacheKey(userID), data, userCacheTTL).Err(); err != nil {
+		logger.Warn.Printf("Failed to cache user %d: %v", userID, err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func connectTestRedis(t *testing.T) context.Context {
+	t.Helper()
+
+	addr := os.Getenv("POTAT_TEST_REDIS")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	client := &db.RedisClient{Client: redis.NewClient(&redis.Options{Addr: addr})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skipf("Redis unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return context.WithValue(context.Background(), RedisKey, client)
+}
+
+func testUserID(t *testing.T, ctx context.Context) int {
+	t.Helper()
+
+	userID := 1_000_000_000 + rand.IntN(1_000_000)
+	t.Cleanup(func() {
+		redis := ctx.Value(RedisKey).(*db.RedisClient)
+		redis.Del(context.Background(), revokedBeforeKey(userID), userCacheKey(userID))
+	})
+
+	return userID
+}
+
+func assertRevoked(t *testing.T, ctx context.Context, auth *Authenticator, token string, revoked bool) {
+	t.Helper()
+
+	claims, err := auth.verifyJWT(token)
+	if err != nil {
+		t.Fatalf("Unexpected error verifying token: %v", err)
+	}
+
+	err = auth.checkRevoked(ctx, claims)
+	switch {
+	case revoked && !errors.Is(err, errRevokedToken):
+		t.Errorf("Expected token to be revoked, got %v", err)
+	case !revoked && err != nil:
+		t.Errorf("Expected token to be valid, got %v", err)
+	}
+}
+
+func TestSession__IssuedAtMillis(t *testing.T) {
+	issued := time.UnixMilli(1_700_000_000_250)
+
+	claims := &potatClaims{
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issued)},
+		IssuedAtMs:       issued.UnixMilli(),
+	}
+	if got := issuedAtMillis(claims); got != 1_700_000_000_250 {
+		t.Errorf("Expected millisecond issue time, got %d", got)
+	}
+
+	claims.IssuedAtMs = 0
+	if got := issuedAtMillis(claims); got != 1_700_000_000_000 {
+		t.Errorf("Expected fallback to the second precision iat, got %d", got)
+	}
+}
+
+func TestSession__RefreshRotatesTokens(t *testing.T) {
+	ctx := connectTestRedis(t)
+	auth := NewAuthenticator("test-secret", nil)
+	userID := testUserID(t, ctx)
+
+	first, err := auth.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+
+	second, err := auth.RefreshSession(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Unexpected error refreshing session: %v", err)
+	}
+
+	if _, err = auth.RefreshSession(ctx, first.RefreshToken); !errors.Is(err, errRevokedToken) {
+		t.Errorf("Expected reused refresh token to be rejected, got %v", err)
+	}
+	if _, err = auth.RefreshSession(ctx, second.AccessToken); !errors.Is(err, errWrongTokenType) {
+		t.Errorf("Expected access token to be rejected as a refresh token, got %v", err)
+	}
+
+	assertRevoked(t, ctx, auth, first.AccessToken, true)
+	assertRevoked(t, ctx, auth, second.AccessToken, false)
+	assertRevoked(t, ctx, auth, second.RefreshToken, false)
+}
+
+func TestSession__ConcurrentRefreshSucceedsOnce(t *testing.T) {
+	ctx := connectTestRedis(t)
+	auth := NewAuthenticator("test-secret", nil)
+	userID := testUserID(t, ctx)
+
+	session, err := auth.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+
+	const attempts = 2
+	results := make(chan error, attempts)
+	start := make(chan struct{})
+	for range attempts {
+		go func() {
+			<-start
+			_, err := auth.RefreshSession(ctx, session.RefreshToken)
+			results <- err
+		}()
+	}
+	close(start)
+
+	succeeded := 0
+	for range attempts {
+		err := <-results
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, errRevokedToken):
+			t.Errorf("Expected a revoked token error, got %v", err)
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("Expected exactly one refresh to succeed, got %d", succeeded)
+	}
+}
+
+func TestSession__RevokeSessionDenylistsBothTokens(t *testing.T) {
+	ctx := connectTestRedis(t)
+	auth := NewAuthenticator("test-secret", nil)
+	userID := testUserID(t, ctx)
+
+	revoked, err := auth.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+	other, err := auth.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+
+	if err = auth.RevokeSession(ctx, revoked.AccessToken); err != nil {
+		t.Fatalf("Unexpected error revoking session: %v", err)
+	}
+
+	assertRevoked(t, ctx, auth, revoked.AccessToken, true)
+	assertRevoked(t, ctx, auth, revoked.RefreshToken, true)
+	assertRevoked(t, ctx, auth, other.AccessToken, false)
+	assertRevoked(t, ctx, auth, other.RefreshToken, false)
+}
+
+func TestSession__RevokeAllSessions(t *testing.T) {
+	ctx := connectTestRedis(t)
+	auth := NewAuthenticator("test-secret", nil)
+	userID := testUserID(t, ctx)
+
+	before, err := auth.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+
+	if err = auth.RevokeAllSessions(ctx, userID); err != nil {
+		t.Fatalf("Unexpected error revoking sessions: %v", err)
+	}
+
+	// A login within the same second as the revocation must still be accepted.
+	time.Sleep(2 * time.Millisecond)
+	after, err := auth.CreateSession(userID)
+	if err != nil {
+		t.Fatalf("Unexpected error creating session: %v", err)
+	}
+
+	assertRevoked(t, ctx, auth, before.AccessToken, true)
+	assertRevoked(t, ctx, auth, before.RefreshToken, true)
+	assertRevoked(t, ctx, auth, after.AccessToken, false)
+	assertRevoked(t, ctx, auth, after.RefreshToken, false)
+}
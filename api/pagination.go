@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// ParsePagination reads the limit and offset query parameters, clamping the limit to maxLimit
+// and falling back to defaultLimit when it is missing or invalid.
+func ParsePagination(request *http.Request, defaultLimit, maxLimit int) (int, int) {
+	query := request.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
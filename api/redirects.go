@@ -0,0 +1,363 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/utils"
+	"github.com/gorilla/mux"
+)
+
+//nolint:revive
+const (
+	MinAliasLength = 3
+	MaxAliasLength = 32
+	MaxRedirectURL = 500
+)
+
+// Generated redirect keys start at 6 base62 characters, growing towards the alias limit as the keyspace fills.
+var redirectKeys = utils.NewKeyAllocator( //nolint:gochecknoglobals // Shared so key growth persists between requests.
+	utils.AlphabetBase62,
+	6,
+	MaxAliasLength,
+	8,
+)
+
+// Aliases that would shadow routes of the redirects server or are otherwise confusing.
+func reservedAliases() []string {
+	return []string{
+		"admin", "api", "auth", "favicon.ico", "health", "login", "logout",
+		"metrics", "qr", "redirect", "redirects", "robots.txt", "static", "stats",
+	}
+}
+
+func isAliasRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+}
+
+// ValidateRedirectAlias checks that a custom alias is a usable, unreserved slug.
+func ValidateRedirectAlias(alias string) []common.ErrorMessage {
+	switch {
+	case len(alias) < MinAliasLength || len(alias) > MaxAliasLength:
+		return []common.ErrorMessage{{
+			Message: fmt.Sprintf("Alias must be between %d and %d characters", MinAliasLength, MaxAliasLength),
+		}}
+	case strings.IndexFunc(alias, func(r rune) bool { return !isAliasRune(r) }) != -1:
+		return []common.ErrorMessage{{Message: "Alias may only contain letters, numbers, '-' and '_'"}}
+	case slices.Contains(reservedAliases(), strings.ToLower(alias)):
+		return []common.ErrorMessage{{Message: fmt.Sprintf("Alias %q is reserved", alias)}}
+	default:
+		return nil
+	}
+}
+
+// ValidateRedirectLimits checks the optional expiry and click limit of a redirect.
+func ValidateRedirectLimits(redirect common.Redirect) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+
+	if redirect.ExpiresAt != nil && redirect.ExpiresAt.Before(time.Now()) {
+		errs = append(errs, common.ErrorMessage{Message: "Expiry must be in the future"})
+	}
+
+	if redirect.MaxClicks != nil && *redirect.MaxClicks <= 0 {
+		errs = append(errs, common.ErrorMessage{Message: "max_clicks must be greater than 0"})
+	}
+
+	return errs
+}
+
+// Schemes redirects may point to.
+func redirectSchemes() []string {
+	return []string{"http", "https"}
+}
+
+// Hosts that only resolve within a private network.
+func privateSuffixes() []string {
+	return []string{".localhost", ".local", ".internal", ".lan", ".home.arpa"}
+}
+
+// Shared address space used by carrier-grade NAT, not covered by netip.Addr.IsPrivate.
+func isSharedAddress(addr netip.Addr) bool {
+	return netip.MustParsePrefix("100.64.0.0/10").Contains(addr)
+}
+
+// Whether the text before the first colon is a scheme rather than a host with a port.
+func hasScheme(raw string) bool {
+	index := strings.IndexByte(raw, ':')
+	if index <= 0 {
+		return false
+	}
+
+	scheme, rest := raw[:index], raw[index+1:]
+	if strings.HasPrefix(rest, "//") {
+		return true
+	}
+	if rest != "" && rest[0] >= '0' && rest[0] <= '9' {
+		return false
+	}
+
+	return strings.IndexFunc(scheme, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '+' && r != '-'
+	}) == -1
+}
+
+// NormalizeRedirectURL parses a redirect target, defaulting to https only when no scheme was given,
+// and rejects targets that are unsafe to redirect to. ownHosts are the hosts this service is served from,
+// so links can't loop back to another short link.
+func NormalizeRedirectURL(raw string, ownHosts []string) (string, []common.ErrorMessage) {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return "", []common.ErrorMessage{{Message: "URL is required"}}
+	case strings.HasPrefix(raw, "//"):
+		raw = "https:" + raw
+	case !hasScheme(raw):
+		raw = "https://" + raw
+	}
+
+	target, err := url.Parse(raw)
+	if err != nil {
+		return "", []common.ErrorMessage{{Message: "URL is not valid"}}
+	}
+
+	target.Scheme = strings.ToLower(target.Scheme)
+	if !slices.Contains(redirectSchemes(), target.Scheme) {
+		return "", []common.ErrorMessage{{Message: fmt.Sprintf("URL scheme %q is not allowed", target.Scheme)}}
+	}
+
+	if target.User != nil {
+		return "", []common.ErrorMessage{{Message: "URLs containing credentials are not allowed"}}
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+	if host == "" {
+		return "", []common.ErrorMessage{{Message: "URL must include a host"}}
+	}
+
+	if errs := validateRedirectHost(host); len(errs) > 0 {
+		return "", errs
+	}
+
+	for _, own := range ownHosts {
+		if own != "" && host == own {
+			return "", []common.ErrorMessage{{Message: "URLs pointing back to this service are not allowed"}}
+		}
+	}
+
+	normalized := target.String()
+	if len(normalized) > MaxRedirectURL {
+		return "", []common.ErrorMessage{{
+			Message: fmt.Sprintf("URL must be at most %d characters", MaxRedirectURL),
+		}}
+	}
+
+	return normalized, nil
+}
+
+func validateRedirectHost(host string) []common.ErrorMessage {
+	private := []common.ErrorMessage{{Message: "URLs pointing to private or local addresses are not allowed"}}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		if !addr.IsGlobalUnicast() || addr.IsPrivate() || isSharedAddress(addr) {
+			return private
+		}
+
+		return nil
+	}
+
+	if host == "localhost" || slices.ContainsFunc(privateSuffixes(), func(suffix string) bool {
+		return strings.HasSuffix(host, suffix)
+	}) {
+		return private
+	}
+
+	// Browsers resolve single labels against the local network, and accept numeric forms
+	// of IPv4 addresses such as 2130706433 or 0x7f.1, neither are public domains.
+	labels := strings.Split(host, ".")
+	tld := labels[len(labels)-1]
+	if len(labels) < 2 || strings.IndexFunc(tld, func(r rune) bool { return r < '0' || r > '9' }) == -1 ||
+		strings.HasPrefix(tld, "0x") {
+		return []common.ErrorMessage{{Message: "URL host must be a public domain"}}
+	}
+
+	return nil
+}
+
+// NormalizeDeniedDomain cleans up a domain to add to the denylist, accepting full URLs for convenience.
+func NormalizeDeniedDomain(domain string) (string, []common.ErrorMessage) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if hasScheme(domain) {
+		if parsed, err := url.Parse(domain); err == nil {
+			domain = parsed.Hostname()
+		}
+	}
+	domain = strings.Trim(domain, ".")
+
+	invalid := []common.ErrorMessage{{Message: "Domain is not valid"}}
+	if domain == "" || len(domain) > 253 {
+		return "", invalid
+	}
+
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || strings.IndexFunc(label, func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-'
+		}) != -1 {
+			return "", invalid
+		}
+	}
+
+	return domain, nil
+}
+
+// RedirectHosts returns the hosts the redirects are served from, which redirects may not point to.
+func RedirectHosts(request *http.Request) []string {
+	hosts := []string{request.Host}
+
+	config := utils.LoadConfig()
+	hosts = append(hosts, config.Redirects.PublicHost, config.API.PublicHost)
+
+	for i, host := range hosts {
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		hosts[i] = strings.TrimSuffix(strings.ToLower(host), ".")
+	}
+
+	return hosts
+}
+
+// ValidateRedirectURL normalizes a redirect target and checks it against the domain denylist.
+func ValidateRedirectURL(
+	ctx context.Context,
+	postgres *db.PostgresClient,
+	request *http.Request,
+	raw string,
+) (string, []common.ErrorMessage, error) {
+	target, errs := NormalizeRedirectURL(raw, RedirectHosts(request))
+	if len(errs) > 0 {
+		return "", errs, nil
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return "", nil, err
+	}
+
+	denied, err := postgres.IsDomainDenied(ctx, strings.TrimSuffix(parsed.Hostname(), "."))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if denied {
+		return "", []common.ErrorMessage{{Message: "Links to this domain are not allowed"}}, nil
+	}
+
+	return target, nil, nil
+}
+
+// CreateGeneratedRedirect stores the redirect under a newly allocated random key.
+func CreateGeneratedRedirect(ctx context.Context, postgres *db.PostgresClient, redirect *common.Redirect) error {
+	_, err := redirectKeys.Allocate(ctx, func(ctx context.Context, key string) (bool, error) {
+		if slices.Contains(reservedAliases(), strings.ToLower(key)) {
+			return false, nil
+		}
+		redirect.Key = key
+
+		return postgres.CreateRedirect(ctx, redirect)
+	})
+
+	return err
+}
+
+// CanManageRedirect reports whether the user owns the redirect, or is an admin.
+func CanManageRedirect(user *common.User, redirect *common.Redirect) bool {
+	if user == nil {
+		return false
+	}
+
+	if common.PermissionLevel(user.Level) >= common.ADMIN { //nolint:gosec
+		return true
+	}
+
+	return redirect.OwnerID != nil && *redirect.OwnerID == user.ID
+}
+
+// UncacheRedirect removes a redirect from the redirects server cache after it changed.
+func UncacheRedirect(ctx context.Context, key string) {
+	redis, ok := ctx.Value(middleware.RedisKey).(*db.RedisClient)
+	if !ok {
+		logger.Error.Println("Redis client not found in context")
+
+		return
+	}
+
+	if err := redis.Del(ctx, key).Err(); err != nil {
+		logger.Warn.Printf("Failed to uncache redirect %s: %v", key, err)
+	}
+}
+
+// LoadManagedRedirect loads the redirect from the {key} route variable and ensures the
+// authenticated user may manage it, writing an error response and returning false otherwise.
+func LoadManagedRedirect(
+	writer http.ResponseWriter,
+	request *http.Request,
+	start time.Time,
+) (*common.Redirect, bool) {
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		GenericResponse(writer, http.StatusUnauthorized, common.GenericResponse[any]{
+			Data:   &[]any{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return nil, false
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return nil, false
+	}
+
+	redirect, err := postgres.GetRedirect(request.Context(), mux.Vars(request)["key"])
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Error fetching redirect"
+		if errors.Is(err, db.ErrPostgresNoRows) {
+			status, message = http.StatusNotFound, "Redirect not found"
+		} else {
+			logger.Error.Printf("Error fetching redirect: %v", err)
+		}
+
+		GenericResponse(writer, status, common.GenericResponse[any]{
+			Data:   &[]any{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return nil, false
+	}
+
+	if !CanManageRedirect(user, redirect) {
+		GenericResponse(writer, http.StatusForbidden, common.GenericResponse[any]{
+			Data:   &[]any{},
+			Errors: &[]common.ErrorMessage{{Message: "You are not allowed to manage this redirect"}},
+		}, start)
+
+		return nil, false
+	}
+
+	return redirect, true
+}
@@ -0,0 +1,109 @@
+// Package delete contains routes for http.MethodDelete requests.
+package delete
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// ChannelBlocksResponse is the response type for the /channels/{platform}/{id}/blocks endpoints.
+type ChannelBlocksResponse = common.GenericResponse[common.Block]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/blocks",
+		Method:  http.MethodDelete,
+		Handler: deleteChannelBlock,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+}
+
+func deleteChannelBlock(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	query := request.URL.Query()
+	block := common.Block{
+		ChannelID:   channel.ChannelID,
+		BlockType:   common.BlockType(strings.ToUpper(query.Get("type"))),
+		CommandName: strings.ToLower(query.Get("command")),
+	}
+
+	if userID := query.Get("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			api.GenericResponse(writer, http.StatusBadRequest, ChannelBlocksResponse{
+				Data:   &[]common.Block{},
+				Errors: &[]common.ErrorMessage{{Message: "Invalid user_id"}},
+			}, start)
+
+			return
+		}
+		block.BlockedUserID = id
+	}
+
+	if errs := api.ValidateBlock(block, user); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	removed, err := postgres.RemoveChannelBlock(request.Context(), block)
+	if err != nil {
+		logger.Error.Printf("Error removing channel block: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Error removing block"}},
+		}, start)
+
+		return
+	}
+
+	if !removed {
+		api.GenericResponse(writer, http.StatusNotFound, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Block not found"}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.channel-blocks-update", api.ChannelBlockEvent{
+		ChannelID: channel.ChannelID,
+		Platform:  channel.Platform,
+		Action:    api.BlocksRemoved,
+		Blocks:    []common.Block{block},
+	})
+
+	api.GenericResponse(writer, http.StatusOK, ChannelBlocksResponse{
+		Data: &[]common.Block{block},
+	}, start)
+}
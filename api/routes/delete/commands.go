@@ -0,0 +1,85 @@
+// Package delete contains routes for http.MethodDelete requests.
+package delete
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+// ChannelCommandsResponse is the response type for the /channels/{platform}/{id}/commands endpoints.
+type ChannelCommandsResponse = common.GenericResponse[common.ChannelCommand]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands/{commandID:[0-9]+}",
+		Method:  http.MethodDelete,
+		Handler: deleteChannelCommand,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+}
+
+func deleteChannelCommand(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	commandID, err := strconv.Atoi(mux.Vars(request)["commandID"])
+	if err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid command ID"}},
+		}, start)
+
+		return
+	}
+
+	deleted, err := postgres.DeleteChannelCommand(request.Context(), channel.ChannelID, commandID)
+	if err != nil {
+		logger.Error.Printf("Error deleting channel command: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Error deleting command"}},
+		}, start)
+
+		return
+	}
+
+	if !deleted {
+		api.GenericResponse(writer, http.StatusNotFound, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Command not found"}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.custom-command-update", api.ChannelCommandEvent{
+		ChannelID: channel.ChannelID,
+		Platform:  channel.Platform,
+		Action:    api.CommandDeleted,
+		Command:   &common.ChannelCommand{CommandID: commandID, ChannelID: channel.ChannelID},
+	})
+
+	api.GenericResponse(writer, http.StatusOK, ChannelCommandsResponse{
+		Data: &[]common.ChannelCommand{},
+	}, start)
+}
@@ -0,0 +1,72 @@
+// Package delete contains routes for http.MethodDelete requests.
+package delete
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+// DenylistResponse is the response type for the /redirects/denylist endpoints.
+type DenylistResponse = common.GenericResponse[common.DeniedDomain]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:     "/redirects/denylist/{domain}",
+		Method:   http.MethodDelete,
+		Handler:  allowDomain,
+		MinLevel: common.ADMIN,
+		Scopes:   []string{api.ScopeRedirectsWrite},
+	})
+}
+
+func allowDomain(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	domain, errs := api.NormalizeDeniedDomain(mux.Vars(request)["domain"])
+	if len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	removed, err := postgres.RemoveDeniedDomain(request.Context(), domain)
+	if err != nil {
+		logger.Error.Printf("Error removing domain from redirect denylist: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Error updating denylist"}},
+		}, start)
+
+		return
+	}
+
+	if !removed {
+		api.GenericResponse(writer, http.StatusNotFound, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Domain is not denied"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, DenylistResponse{
+		Data: &[]common.DeniedDomain{},
+	}, start)
+}
@@ -0,0 +1,83 @@
+// Package delete contains routes for http.MethodDelete requests.
+package delete
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+// APIKeysResponse is the response type for the /twitch/me/keys endpoints.
+type APIKeysResponse = common.GenericResponse[common.APIKey]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/twitch/me/keys/{keyID:[0-9]+}",
+		Method:  http.MethodDelete,
+		Handler: deleteAPIKey,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeKeysManage},
+	})
+}
+
+func deleteAPIKey(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	keyID, err := strconv.Atoi(mux.Vars(request)["keyID"])
+	if err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid key ID"}},
+		}, start)
+
+		return
+	}
+
+	deleted, err := postgres.DeleteAPIKey(request.Context(), user.ID, keyID)
+	if err != nil {
+		logger.Error.Printf("Error deleting API key: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Error revoking API key"}},
+		}, start)
+
+		return
+	}
+
+	if !deleted {
+		api.GenericResponse(writer, http.StatusNotFound, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "API key not found"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, APIKeysResponse{
+		Data: &[]common.APIKey{},
+	}, start)
+}
@@ -0,0 +1,68 @@
+// Package delete contains routes for http.MethodDelete requests.
+package delete
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// RedirectsResponse is the response type for the /redirects/{key} endpoint.
+type RedirectsResponse = common.GenericResponse[common.Redirect]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/redirects/{key}",
+		Method:  http.MethodDelete,
+		Handler: deleteRedirect,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeRedirectsWrite},
+	})
+}
+
+func deleteRedirect(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	redirect, ok := api.LoadManagedRedirect(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	deleted, err := postgres.DeleteRedirect(request.Context(), redirect.Key)
+	if err != nil {
+		logger.Error.Printf("Error deleting redirect: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &[]common.ErrorMessage{{Message: "Error deleting redirect"}},
+		}, start)
+
+		return
+	}
+
+	if !deleted {
+		api.GenericResponse(writer, http.StatusNotFound, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &[]common.ErrorMessage{{Message: "Redirect not found"}},
+		}, start)
+
+		return
+	}
+
+	api.UncacheRedirect(request.Context(), redirect.Key)
+
+	api.GenericResponse(writer, http.StatusOK, RedirectsResponse{
+		Data: &[]common.Redirect{},
+	}, start)
+}
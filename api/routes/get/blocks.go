@@ -0,0 +1,81 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// ChannelBlocksResponse is the response type for the /channels/{platform}/{id}/blocks endpoint.
+type ChannelBlocksResponse = common.GenericResponse[common.Block]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/blocks",
+		Method:  http.MethodGet,
+		Handler: getChannelBlocks,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsRead},
+	})
+}
+
+func getChannelBlocks(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, err := api.GetChannelFromRequest(request)
+	if err != nil {
+		status, message := api.ChannelLookupStatus(err)
+		api.GenericResponse(writer, status, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	blockType := common.BlockType(strings.ToUpper(request.URL.Query().Get("type")))
+	if blockType != "" && !blockType.IsValid() {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid block type"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	limit, offset := api.ParsePagination(request, 50, 500)
+
+	blocks, total, err := postgres.ListChannelBlocks(request.Context(), channel.ChannelID, blockType, limit, offset)
+	if err != nil {
+		logger.Error.Printf("Error listing channel blocks: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching blocks"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, ChannelBlocksResponse{
+		Data: &blocks,
+		Pagination: &common.Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, start)
+}
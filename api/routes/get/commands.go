@@ -0,0 +1,47 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// ChannelCommandsResponse is the response type for the /channels/{platform}/{id}/commands endpoint.
+type ChannelCommandsResponse = common.GenericResponse[common.ChannelCommand]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands",
+		Method:  http.MethodGet,
+		Handler: getChannelCommands,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsRead},
+	})
+}
+
+func getChannelCommands(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, err := api.GetChannelFromRequest(request)
+	if err != nil {
+		status, message := api.ChannelLookupStatus(err)
+		api.GenericResponse(writer, status, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	commands := channel.Commands
+	if commands == nil {
+		commands = &[]common.ChannelCommand{}
+	}
+
+	api.GenericResponse(writer, http.StatusOK, ChannelCommandsResponse{
+		Data: commands,
+	}, start)
+}
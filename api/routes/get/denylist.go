@@ -0,0 +1,52 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// DenylistResponse is the response type for the /redirects/denylist endpoints.
+type DenylistResponse = common.GenericResponse[common.DeniedDomain]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:     "/redirects/denylist",
+		Method:   http.MethodGet,
+		Handler:  getDenylist,
+		MinLevel: common.ADMIN,
+		Scopes:   []string{api.ScopeRedirectsRead},
+	})
+}
+
+func getDenylist(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	domains, err := postgres.ListDeniedDomains(request.Context())
+	if err != nil {
+		logger.Error.Printf("Error fetching redirect denylist: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching denylist"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, DenylistResponse{
+		Data: &domains,
+	}, start)
+}
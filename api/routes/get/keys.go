@@ -0,0 +1,62 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// APIKeysResponse is the response type for the /twitch/me/keys endpoint.
+type APIKeysResponse = common.GenericResponse[common.APIKey]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/twitch/me/keys",
+		Method:  http.MethodGet,
+		Handler: getAPIKeys,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeKeysManage},
+	})
+}
+
+func getAPIKeys(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	keys, err := postgres.ListAPIKeys(request.Context(), user.ID)
+	if err != nil {
+		logger.Error.Printf("Error listing API keys: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Error listing API keys"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, APIKeysResponse{
+		Data: &keys,
+	}, start)
+}
@@ -0,0 +1,214 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// LeaderboardResponse is the response type for the /potatoes/leaderboard endpoints.
+type LeaderboardResponse = common.GenericResponse[common.LeaderboardEntry]
+
+const maxLeaderboardRange = 25
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/potatoes/leaderboard",
+		Method:  http.MethodGet,
+		Handler: getLeaderboard,
+		UseAuth: false,
+	})
+	api.SetRoute(api.Route{
+		Path:    "/potatoes/leaderboard/me",
+		Method:  http.MethodGet,
+		Handler: getLeaderboardAroundMe,
+		UseAuth: true,
+	})
+}
+
+func encodeLeaderboardCursor(userID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(userID)))
+}
+
+func decodeLeaderboardCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(raw))
+}
+
+func parseLeaderboardSort(request *http.Request) (common.LeaderboardSort, bool) {
+	sort := common.LeaderboardSort(strings.ToLower(request.URL.Query().Get("sort")))
+	if sort == "" {
+		return common.SortPotatoCount, true
+	}
+
+	return sort, sort.IsValid()
+}
+
+func getLeaderboard(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	sort, ok := parseLeaderboardSort(request)
+	if !ok {
+		api.GenericResponse(writer, http.StatusBadRequest, LeaderboardResponse{
+			Data:   &[]common.LeaderboardEntry{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid sort"}},
+		}, start)
+
+		return
+	}
+
+	redis, ok := request.Context().Value(middleware.RedisKey).(*db.RedisClient)
+	if !ok {
+		logger.Error.Println("Redis client not found in context")
+
+		return
+	}
+
+	limit, _ := api.ParsePagination(request, 25, 100)
+
+	var offset int64
+	if cursor := request.URL.Query().Get("cursor"); cursor != "" {
+		userID, err := decodeLeaderboardCursor(cursor)
+		if err != nil {
+			api.GenericResponse(writer, http.StatusBadRequest, LeaderboardResponse{
+				Data:   &[]common.LeaderboardEntry{},
+				Errors: &[]common.ErrorMessage{{Message: "Invalid cursor"}},
+			}, start)
+
+			return
+		}
+
+		position, err := redis.GetLeaderboardPosition(request.Context(), sort, userID)
+		if err != nil {
+			status, message := http.StatusInternalServerError, "Error fetching leaderboard"
+			if errors.Is(err, db.ErrRedisNil) {
+				status, message = http.StatusBadRequest, "Cursor has expired"
+			} else {
+				logger.Error.Printf("Error resolving leaderboard cursor: %v", err)
+			}
+
+			api.GenericResponse(writer, status, LeaderboardResponse{
+				Data:   &[]common.LeaderboardEntry{},
+				Errors: &[]common.ErrorMessage{{Message: message}},
+			}, start)
+
+			return
+		}
+
+		offset = position + 1
+	}
+
+	entries, total, err := redis.GetLeaderboardRange(request.Context(), sort, offset, offset+int64(limit)-1)
+	if err != nil {
+		logger.Error.Printf("Error fetching leaderboard: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, LeaderboardResponse{
+			Data:   &[]common.LeaderboardEntry{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching leaderboard"}},
+		}, start)
+
+		return
+	}
+
+	pagination := &common.Pagination{
+		Total:  int(total),
+		Limit:  limit,
+		Offset: int(offset),
+	}
+	if len(entries) > 0 && offset+int64(len(entries)) < total {
+		pagination.Cursor = encodeLeaderboardCursor(entries[len(entries)-1].ID)
+	}
+
+	api.GenericResponse(writer, http.StatusOK, LeaderboardResponse{
+		Data:       &entries,
+		Pagination: pagination,
+	}, start)
+}
+
+func getLeaderboardAroundMe(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, LeaderboardResponse{
+			Data:   &[]common.LeaderboardEntry{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	sort, ok := parseLeaderboardSort(request)
+	if !ok {
+		api.GenericResponse(writer, http.StatusBadRequest, LeaderboardResponse{
+			Data:   &[]common.LeaderboardEntry{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid sort"}},
+		}, start)
+
+		return
+	}
+
+	redis, ok := request.Context().Value(middleware.RedisKey).(*db.RedisClient)
+	if !ok {
+		logger.Error.Println("Redis client not found in context")
+
+		return
+	}
+
+	around, err := strconv.Atoi(request.URL.Query().Get("range"))
+	if err != nil || around < 0 {
+		around = 5
+	}
+	around = min(around, maxLeaderboardRange)
+
+	position, err := redis.GetLeaderboardPosition(request.Context(), sort, user.ID)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Error fetching leaderboard"
+		if errors.Is(err, db.ErrRedisNil) {
+			status, message = http.StatusNotFound, "You are not on the leaderboard yet"
+		} else {
+			logger.Error.Printf("Error fetching leaderboard position: %v", err)
+		}
+
+		api.GenericResponse(writer, status, LeaderboardResponse{
+			Data:   &[]common.LeaderboardEntry{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	first := max(position-int64(around), 0)
+	entries, total, err := redis.GetLeaderboardRange(request.Context(), sort, first, position+int64(around))
+	if err != nil {
+		logger.Error.Printf("Error fetching leaderboard: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, LeaderboardResponse{
+			Data:   &[]common.LeaderboardEntry{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching leaderboard"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, LeaderboardResponse{
+		Data: &entries,
+		Pagination: &common.Pagination{
+			Total:  int(total),
+			Limit:  around*2 + 1,
+			Offset: int(first),
+		},
+	}, start)
+}
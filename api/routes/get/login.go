@@ -2,16 +2,19 @@
 package get
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
 	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
 	"github.com/Potat-Industries/potat-api/common/logger"
 	"github.com/Potat-Industries/potat-api/common/utils"
 	"github.com/google/uuid"
@@ -22,9 +25,25 @@ const (
 	twitchOauthURI   = "https://id.twitch.tv/oauth2/authorize"
 	twitchOauthToken = "https://id.twitch.tv/oauth2/token"
 	scopes           = "channel:bot chat:read user:read:moderated_channels channel:manage:broadcast channel:manage:redemptions channel:read:subscriptions moderator:read:followers channel:read:hype_train channel:read:guest_star"
+	stateTTL         = 20 * time.Second
 )
 
-var replyDeny sync.Map //nolint:gochecknoglobals // Used to prevent replay attacks on the oauth flow.
+var errUserNotCreated = errors.New("user was not created")
+
+type createUserJob struct {
+	Type       string           `json:"type"`
+	Platform   common.Platforms `json:"platform"`
+	PlatformID string           `json:"platform_id"`
+	Login      string           `json:"login"`
+}
+
+type loginMessage struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	Name         string `json:"name"`
+	ExpiresIn    int    `json:"expires_in"`
+	UserID       int    `json:"user_id"`
+}
 
 func init() {
 	api.SetRoute(api.Route{
@@ -35,15 +54,117 @@ func init() {
 	})
 }
 
-func setReplyDeny() string {
+// setReplyDeny stores a single use oauth state in Redis, so any API replica can complete the flow.
+func setReplyDeny(ctx context.Context, redis *db.RedisClient) (string, error) {
 	nonce := uuid.New().String()
-	replyDeny.Store(nonce, true)
-	go func(n string) {
-		time.Sleep(20 * time.Second)
-		replyDeny.Delete(n)
-	}(nonce)
 
-	return nonce
+	err := redis.SetEx(ctx, "oauth:state:"+nonce, "1", stateTTL).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return nonce, nil
+}
+
+// consumeReplyDeny deletes the oauth state, reporting whether it existed.
+func consumeReplyDeny(ctx context.Context, redis *db.RedisClient, state string) bool {
+	if state == "" {
+		return false
+	}
+
+	deleted, err := redis.Del(ctx, "oauth:state:"+state).Result()
+	if err != nil {
+		logger.Error.Printf("Error consuming oauth state: %v", err)
+
+		return false
+	}
+
+	return deleted == 1
+}
+
+// loginOrigin returns the origin of the site that opened the login popup, which is the API host without "api.".
+func loginOrigin(oauthURI string) string {
+	parsed, err := url.Parse(oauthURI)
+	if err != nil || parsed.Host == "" {
+		return strings.TrimSuffix(strings.Replace(oauthURI, "api.", "", 1), "/")
+	}
+
+	return fmt.Sprintf("%s://%s", parsed.Scheme, strings.TrimPrefix(parsed.Host, "api."))
+}
+
+func resolveLoginUser(
+	ctx context.Context,
+	postgres *db.PostgresClient,
+	validation *common.TwitchValidation,
+) (*common.User, error) {
+	user, err := postgres.GetUserByConnection(ctx, validation.UserID, common.TWITCH)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, db.ErrPostgresNoRows) {
+		return nil, err
+	}
+
+	// Unknown user, ask PotatBotat to create them and look them up again
+	job, err := json.Marshal(createUserJob{
+		Type:       "create-user",
+		Platform:   common.TWITCH,
+		PlatformID: validation.UserID,
+		Login:      validation.Login,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = utils.BridgeRequest(5*time.Second, string(job)); err != nil {
+		return nil, err
+	}
+
+	user, err = postgres.GetUserByConnection(ctx, validation.UserID, common.TWITCH)
+	if errors.Is(err, db.ErrPostgresNoRows) {
+		return nil, errUserNotCreated
+	}
+
+	return user, err
+}
+
+func writeLoginPage(writer http.ResponseWriter, origin string, message loginMessage) {
+	// json.Marshal escapes <, > and &, so the values are safe to embed in a script tag
+	payload, err := json.Marshal(message)
+	if err != nil {
+		logger.Error.Println("Failed to encode login message: ", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	target, err := json.Marshal(origin)
+	if err != nil {
+		logger.Error.Println("Failed to encode login origin: ", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	// auth successful, close the popup and send token backarino
+	html := fmt.Sprintf(`
+		<script>
+			if (window.opener) {
+				window.opener.postMessage(%s, %s);
+				window.close();
+			}
+		</script>
+		`,
+		payload,
+		target,
+	)
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(http.StatusOK)
+	_, err = writer.Write([]byte(html))
+	if err != nil {
+		logger.Warn.Println("Failed to write document: ", err)
+	}
 }
 
 func handleErr(w http.ResponseWriter, start time.Time) {
@@ -55,28 +176,50 @@ func handleErr(w http.ResponseWriter, start time.Time) {
 	}
 }
 
-func twitchLoginHandler(writer http.ResponseWriter, request *http.Request) { //nolint:cyclop
+func twitchLoginHandler(writer http.ResponseWriter, request *http.Request) { //nolint:cyclop,gocognit
 	start := time.Now()
 
 	config := utils.LoadConfig()
 
 	defer handleErr(writer, start)
 
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	redis, ok := request.Context().Value(middleware.RedisKey).(*db.RedisClient)
+	if !ok {
+		logger.Error.Println("Redis client not found in context")
+
+		return
+	}
+
 	query := request.URL.Query()
 	code := query.Get("code")
 	state := query.Get("state")
 
-	redirectURI := fmt.Sprintf("%slogin", config.Twitch.OauthURI)
+	redirectURI := strings.TrimSuffix(config.Twitch.OauthURI, "/") + "/login"
 
 	// Redirect to twitch oauth
 	if code == "" {
+		nonce, err := setReplyDeny(request.Context(), redis)
+		if err != nil {
+			logger.Error.Printf("Error storing oauth state: %v", err)
+			http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+
 		params := url.Values{
 			"client_id":     {config.Twitch.ClientID},
 			"force_verify":  {"false"},
 			"redirect_uri":  {redirectURI},
 			"response_type": {"code"},
 			"scope":         {scopes},
-			"state":         {setReplyDeny()},
+			"state":         {nonce},
 		}.Encode()
 		uri := fmt.Sprintf("%s?%s", twitchOauthURI, params)
 		http.Redirect(writer, request, uri, http.StatusFound)
@@ -85,12 +228,11 @@ func twitchLoginHandler(writer http.ResponseWriter, request *http.Request) { //n
 	}
 
 	// Disallow replay attacks
-	if _, ok := replyDeny.Load(state); !ok {
+	if !consumeReplyDeny(request.Context(), redis, state) {
 		http.Error(writer, "Forbidden", http.StatusForbidden)
 
 		return
 	}
-	replyDeny.Delete(state)
 
 	data := url.Values{
 		"client_id":     {config.Twitch.ClientID},
@@ -111,6 +253,7 @@ func twitchLoginHandler(writer http.ResponseWriter, request *http.Request) { //n
 
 		return
 	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	client := &http.Client{
 		Timeout: 10 * time.Second,
@@ -139,7 +282,7 @@ func twitchLoginHandler(writer http.ResponseWriter, request *http.Request) { //n
 	ok, validation, err := utils.ValidateHelixToken(
 		request.Context(),
 		tokenData.AccessToken,
-		false,
+		true,
 	)
 	if err != nil || !ok || validation.UserID == "" {
 		api.GenericResponse(writer, http.StatusUnauthorized, AuthorizedUserResponse{
@@ -150,31 +293,48 @@ func twitchLoginHandler(writer http.ResponseWriter, request *http.Request) { //n
 		return
 	}
 
-	// check if user exists in the database
-	// user, err := db.Postgres.GetUserByConnection(
-	// 	r.Context(),
-	// 	validation.UserID,
-	// 	common.TWITCH,
-	// )
-	// if not send rabbitmq message to create user on potat
-	// if user created update data, set oauth token
+	user, err := resolveLoginUser(request.Context(), postgres, validation)
+	if err != nil {
+		logger.Error.Printf("Error resolving user for Twitch login %s: %v", validation.UserID, err)
+		api.GenericResponse(writer, http.StatusInternalServerError, AuthorizedUserResponse{
+			Data:   &[]SiteUserData{},
+			Errors: &[]common.ErrorMessage{{Message: "Failed to load user"}},
+		}, start)
 
-	// auth successful, close the popup and send token backarino
-	html := fmt.Sprintf(`
-		<script>
-			if (window.opener) {
-				window.opener.postMessage(%s, '%s');
-				window.close();
-			}
-		</script>
-		`,
-		string("token and stuff lol"),
-		strings.Replace(config.Twitch.OauthURI, "api.", "", 1),
-	)
-	writer.Header().Set("Content-Type", "text/html")
-	writer.WriteHeader(http.StatusOK)
-	_, err = writer.Write([]byte(html))
+		return
+	}
+
+	err = postgres.UpsertOAuthToken(request.Context(), &tokenData, common.PlatformOauth{
+		PlatformID: validation.UserID,
+		Platform:   common.TWITCH,
+	})
 	if err != nil {
-		logger.Warn.Println("Failed to write document: ", err)
+		logger.Error.Printf("Error saving oauth token for %s: %v", validation.UserID, err)
+		api.GenericResponse(writer, http.StatusInternalServerError, AuthorizedUserResponse{
+			Data:   &[]SiteUserData{},
+			Errors: &[]common.ErrorMessage{{Message: "Failed to save access token"}},
+		}, start)
+
+		return
+	}
+
+	authenticator := middleware.NewAuthenticator(config.Twitch.ClientSecret, api.GenericResponse)
+	session, err := authenticator.CreateSession(user.ID)
+	if err != nil {
+		logger.Error.Printf("Error creating session for user %d: %v", user.ID, err)
+		api.GenericResponse(writer, http.StatusInternalServerError, AuthorizedUserResponse{
+			Data:   &[]SiteUserData{},
+			Errors: &[]common.ErrorMessage{{Message: "Failed to create session"}},
+		}, start)
+
+		return
 	}
+
+	writeLoginPage(writer, loginOrigin(config.Twitch.OauthURI), loginMessage{
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    session.ExpiresIn,
+		Name:         user.Display,
+		UserID:       user.ID,
+	})
 }
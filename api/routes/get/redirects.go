@@ -0,0 +1,169 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+// RedirectStatsResponse is the response type for the /redirects/{key}/stats endpoint.
+type RedirectStatsResponse = common.GenericResponse[common.RedirectStats]
+
+// RedirectsResponse is the response type for the /redirects endpoint.
+type RedirectsResponse = common.GenericResponse[common.Redirect]
+
+const (
+	defaultStatsHours = 48
+	maxStatsHours     = 24 * 14
+	defaultStatsDays  = 30
+	maxStatsDays      = 365
+)
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/redirects",
+		Method:  http.MethodGet,
+		Handler: getOwnedRedirects,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeRedirectsRead},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/redirects/{key}/stats",
+		Method:  http.MethodGet,
+		Handler: getRedirectStats,
+		UseAuth: false,
+	})
+}
+
+func parseStatsRange(request *http.Request, name string, fallback, limit int) int {
+	value, err := strconv.Atoi(request.URL.Query().Get(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+
+	return min(value, limit)
+}
+
+func getRedirectStats(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	key := mux.Vars(request)["key"]
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	clickhouse, ok := request.Context().Value(middleware.ClickhouseKey).(*db.ClickhouseClient)
+	if !ok || clickhouse == nil {
+		logger.Error.Println("Clickhouse client not found in context")
+
+		return
+	}
+
+	redirect, err := postgres.GetRedirect(request.Context(), key)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Error fetching redirect"
+		if errors.Is(err, db.ErrPostgresNoRows) {
+			status, message = http.StatusNotFound, "Redirect not found"
+		} else {
+			logger.Error.Printf("Error fetching redirect: %v", err)
+		}
+
+		api.GenericResponse(writer, status, RedirectStatsResponse{
+			Data:   &[]common.RedirectStats{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	// Stats of owned links are private to their owner
+	user, _ := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if redirect.OwnerID != nil && !api.CanManageRedirect(user, redirect) {
+		api.GenericResponse(writer, http.StatusForbidden, RedirectStatsResponse{
+			Data:   &[]common.RedirectStats{},
+			Errors: &[]common.ErrorMessage{{Message: "You are not allowed to view this redirect"}},
+		}, start)
+
+		return
+	}
+
+	hours := parseStatsRange(request, "hours", defaultStatsHours, maxStatsHours)
+	days := parseStatsRange(request, "days", defaultStatsDays, maxStatsDays)
+
+	now := time.Now()
+	stats, err := clickhouse.GetRedirectStats(
+		request.Context(),
+		key,
+		now.Add(-time.Duration(hours)*time.Hour).Truncate(time.Hour),
+		now.AddDate(0, 0, -days).Truncate(24*time.Hour),
+	)
+	if err != nil {
+		logger.Error.Printf("Error fetching redirect stats: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, RedirectStatsResponse{
+			Data:   &[]common.RedirectStats{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching redirect stats"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, RedirectStatsResponse{
+		Data: &[]common.RedirectStats{*stats},
+	}, start)
+}
+
+func getOwnedRedirects(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	limit, offset := api.ParsePagination(request, 50, 200)
+
+	redirects, total, err := postgres.ListRedirectsByOwner(request.Context(), user.ID, limit, offset)
+	if err != nil {
+		logger.Error.Printf("Error listing redirects: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &[]common.ErrorMessage{{Message: "Error listing redirects"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, RedirectsResponse{
+		Data: &redirects,
+		Pagination: &common.Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, start)
+}
@@ -0,0 +1,100 @@
+// Package get contains routes for http.MethodGet requests.
+package get
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+// ChannelSettingsResponse is the response type for the /channels/{platform}/{id}/settings endpoint.
+type ChannelSettingsResponse = common.GenericResponse[common.ChannelSettings]
+
+// CommandSettingsResponse is the response type for the /channels/{platform}/{id}/commands/{command}/settings endpoint.
+type CommandSettingsResponse = common.GenericResponse[common.CommandSettings]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/settings",
+		Method:  http.MethodGet,
+		Handler: getChannelSettings,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsRead},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands/{command}/settings",
+		Method:  http.MethodGet,
+		Handler: getCommandSettings,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsRead},
+	})
+}
+
+func getChannelSettings(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, err := api.GetChannelFromRequest(request)
+	if err != nil {
+		status, message := api.ChannelLookupStatus(err)
+		api.GenericResponse(writer, status, ChannelSettingsResponse{
+			Data:   &[]common.ChannelSettings{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, ChannelSettingsResponse{
+		Data: &[]common.ChannelSettings{channel.Settings},
+	}, start)
+}
+
+func getCommandSettings(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, err := api.GetChannelFromRequest(request)
+	if err != nil {
+		status, message := api.ChannelLookupStatus(err)
+		api.GenericResponse(writer, status, CommandSettingsResponse{
+			Data:   &[]common.CommandSettings{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	command := strings.ToLower(mux.Vars(request)["command"])
+
+	settings, err := postgres.GetCommandSettings(request.Context(), channel.ChannelID, command)
+	if errors.Is(err, db.ErrPostgresNoRows) {
+		defaults := common.DefaultCommandSettings(channel.ChannelID, command)
+		settings = &defaults
+	} else if err != nil {
+		logger.Error.Printf("Error fetching command settings: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, CommandSettingsResponse{
+			Data:   &[]common.CommandSettings{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching command settings"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, CommandSettingsResponse{
+		Data: &[]common.CommandSettings{*settings},
+	}, start)
+}
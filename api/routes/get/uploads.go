@@ -0,0 +1,118 @@
+package get
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// UploadsResponse is the response type for the /twitch/me/uploads endpoint.
+type UploadsResponse = common.GenericResponse[common.Upload]
+
+// UploadUsageResponse is the response type for the /twitch/me/uploads/usage endpoint.
+type UploadUsageResponse = common.GenericResponse[common.UploadUsage]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/twitch/me/uploads",
+		Method:  http.MethodGet,
+		Handler: getOwnedUploads,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeUploadsRead},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/twitch/me/uploads/usage",
+		Method:  http.MethodGet,
+		Handler: getUploadUsage,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeUploadsRead},
+	})
+}
+
+func getOwnedUploads(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, UploadsResponse{
+			Data:   &[]common.Upload{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	limit, offset := api.ParsePagination(request, 50, 200)
+
+	uploads, total, err := postgres.ListUploadsByOwner(request.Context(), user.ID, limit, offset)
+	if err != nil {
+		logger.Error.Printf("Error listing uploads: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, UploadsResponse{
+			Data:   &[]common.Upload{},
+			Errors: &[]common.ErrorMessage{{Message: "Error listing uploads"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, UploadsResponse{
+		Data: &uploads,
+		Pagination: &common.Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+	}, start)
+}
+
+func getUploadUsage(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, UploadUsageResponse{
+			Data:   &[]common.UploadUsage{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	files, used, err := postgres.GetUploadUsage(request.Context(), user.ID)
+	if err != nil {
+		logger.Error.Printf("Error getting upload usage: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, UploadUsageResponse{
+			Data:   &[]common.UploadUsage{},
+			Errors: &[]common.ErrorMessage{{Message: "Error getting upload usage"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, UploadUsageResponse{
+		Data: &[]common.UploadUsage{{
+			UploadLimits: common.GetUploadLimits(common.PermissionLevel(user.Level)), //nolint:gosec
+			Files:        files,
+			Used:         used,
+		}},
+	}, start)
+}
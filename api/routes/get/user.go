@@ -3,7 +3,6 @@ package get
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -185,151 +184,89 @@ func tidyPotatoInfo(
 	}
 }
 
-func loadUser(ctx context.Context, user string) UserInfo { //nolint:gocognit,cyclop
+func loadUsers(ctx context.Context, usernames []string) ([]UserInfo, error) {
 	postgres, ok := ctx.Value(middleware.PostgresKey).(*db.PostgresClient)
 	if !ok {
-		logger.Error.Println("Postgres client not found in context")
-
-		return UserInfo{}
+		return nil, middleware.ErrMissingContext
 	}
 
 	redis, ok := ctx.Value(middleware.RedisKey).(*db.RedisClient)
 	if !ok {
-		logger.Error.Println("Redis client not found in context")
-
-		return UserInfo{}
+		return nil, middleware.ErrMissingContext
 	}
 
-	var wg sync.WaitGroup
-
-	wg.Add(9)
-
-	var userData *common.User
-	var channelData *common.Channel
-	var potatData *common.PotatoData
-	var lastPotato int
-	var lastCDR int
-	var lastTrample int
-	var lastSteal int
-	var lastEat int
-	var lastQuiz int
-
-	go func() {
-		defer wg.Done()
+	// Redis key prefixes of the potato cooldowns, in the order tidyPotatoInfo takes them.
+	potatoCooldowns := [...]string{"potato", "cdr", "trample", "steal", "eat", "quiz"}
 
-		data, err := postgres.GetUserByName(ctx, user)
-		if err != nil && !errors.Is(err, db.ErrPostgresNoRows) {
-			logger.Warn.Println("Error fetching user data: ", err)
-		} else {
-			userData = data
+	keys := make([]db.LoaderKey, len(usernames))
+	cooldownKeys := make([]string, 0, len(usernames)*len(potatoCooldowns))
+	for i := range usernames {
+		keys[i] = db.LoaderKey{Username: &usernames[i]}
+		for _, prefix := range potatoCooldowns {
+			cooldownKeys = append(cooldownKeys, prefix+":"+usernames[i])
 		}
-	}()
-
-	go func() {
-		defer wg.Done()
+	}
 
-		data, err := postgres.GetChannelByName(ctx, user, common.TWITCH)
-		if err != nil && !errors.Is(err, db.ErrPostgresNoRows) {
-			logger.Warn.Println("Error fetching channel data: ", err)
-		} else {
-			channelData = data
-		}
-	}()
+	var wg sync.WaitGroup
 
-	go func() {
-		defer wg.Done()
+	wg.Add(4)
 
-		data, err := postgres.GetPotatoData(ctx, user)
-		if err != nil && !errors.Is(err, db.ErrPostgresNoRows) {
-			logger.Warn.Println("Error fetching potato data: ", err)
-		} else {
-			potatData = data
-		}
-	}()
+	var users []*common.User
+	var channels []*common.Channel
+	var potatoes []*common.PotatoData
+	var cooldowns []int
+	var userErr, channelErr, potatoErr, cooldownErr error
 
 	go func() {
 		defer wg.Done()
-
-		data, err := redis.Get(ctx, fmt.Sprintf("potato:%s", user)).Int()
-		if err != nil && !errors.Is(err, db.ErrRedisNil) {
-			logger.Warn.Println("Error fetching last potato: ", err)
-		} else {
-			lastPotato = data
-		}
+		users, userErr = postgres.LoadUsers(ctx, keys)
 	}()
 
 	go func() {
 		defer wg.Done()
-
-		data, err := redis.Get(ctx, fmt.Sprintf("cdr:%s", user)).Int()
-		if err != nil && !errors.Is(err, db.ErrRedisNil) {
-			logger.Warn.Println("Error fetching last cdr: ", err)
-		} else {
-			lastCDR = data
-		}
+		channels, channelErr = postgres.LoadChannels(ctx, keys)
 	}()
 
 	go func() {
 		defer wg.Done()
-
-		data, err := redis.Get(ctx, fmt.Sprintf("trample:%s", user)).Int()
-		if err != nil && !errors.Is(err, db.ErrRedisNil) {
-			logger.Warn.Println("Error fetching last trample: ", err)
-		} else {
-			lastTrample = data
-		}
+		potatoes, potatoErr = postgres.LoadPotatoData(ctx, keys)
 	}()
 
 	go func() {
 		defer wg.Done()
-
-		data, err := redis.Get(ctx, fmt.Sprintf("steal:%s", user)).Int()
-		if err != nil && !errors.Is(err, db.ErrRedisNil) {
-			logger.Warn.Println("Error fetching last steal: ", err)
-		} else {
-			lastSteal = data
-		}
+		cooldowns, cooldownErr = redis.MGetInts(ctx, cooldownKeys...)
 	}()
 
-	go func() {
-		defer wg.Done()
+	wg.Wait()
 
-		data, err := redis.Get(ctx, fmt.Sprintf("eat:%s", user)).Int()
-		if err != nil && !errors.Is(err, db.ErrRedisNil) {
-			logger.Warn.Println("Error fetching last eat: ", err)
-		} else {
-			lastEat = data
-		}
-	}()
+	if userErr != nil {
+		return nil, userErr
+	}
+	if channelErr != nil {
+		logger.Warn.Println("Error fetching channel data: ", channelErr)
+		channels = make([]*common.Channel, len(usernames))
+	}
+	if potatoErr != nil {
+		logger.Warn.Println("Error fetching potato data: ", potatoErr)
+		potatoes = make([]*common.PotatoData, len(usernames))
+	}
+	if cooldownErr != nil {
+		logger.Warn.Println("Error fetching potato cooldowns: ", cooldownErr)
+		cooldowns = make([]int, len(cooldownKeys))
+	}
 
-	go func() {
-		defer wg.Done()
+	infos := make([]UserInfo, len(usernames))
+	for i := range usernames {
+		last := cooldowns[i*len(potatoCooldowns) : (i+1)*len(potatoCooldowns)]
 
-		data, err := redis.Get(ctx, fmt.Sprintf("quiz:%s", user)).Int()
-		if err != nil && !errors.Is(err, db.ErrRedisNil) {
-			logger.Warn.Println("Error fetching last quiz: ", err)
-		} else {
-			lastQuiz = data
+		infos[i] = UserInfo{
+			User:     users[i],
+			Channel:  channels[i],
+			Potatoes: tidyPotatoInfo(potatoes[i], last[0], last[1], last[2], last[3], last[4], last[5]),
 		}
-	}()
-
-	wg.Wait()
-
-	potatoes := tidyPotatoInfo(
-		potatData,
-		lastPotato,
-		lastCDR,
-		lastTrample,
-		lastSteal,
-		lastEat,
-		lastQuiz,
-	)
-
-	return UserInfo{
-		User:     userData,
-		Channel:  channelData,
-		Potatoes: potatoes,
 	}
+
+	return infos, nil
 }
 
 func getUsers(writer http.ResponseWriter, request *http.Request) {
@@ -364,27 +301,18 @@ func getUsers(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	dataChan := make(chan UserInfo, len(userArray))
+	dataArray, err := loadUsers(request.Context(), userArray)
+	if err != nil {
+		logger.Error.Printf("Error loading users: %v", err)
 
-	var dataArray []UserInfo
-	var wg sync.WaitGroup
-
-	wg.Add(len(userArray))
-	for _, user := range userArray {
-		go func(ctx context.Context) {
-			defer wg.Done()
-			info := loadUser(ctx, user)
-			dataChan <- info
-		}(request.Context())
-	}
+		res := UsersResponse{
+			Data:   &[]UserInfo{},
+			Errors: &[]common.ErrorMessage{{Message: "Error loading users"}},
+		}
 
-	go func() {
-		wg.Wait()
-		close(dataChan)
-	}()
+		api.GenericResponse(writer, http.StatusInternalServerError, res, start)
 
-	for userData := range dataChan {
-		dataArray = append(dataArray, userData)
+		return
 	}
 
 	if dataArray[0].User == nil {
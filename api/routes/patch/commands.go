@@ -0,0 +1,207 @@
+// Package patch contains routes for http.MethodPatch requests.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+// ChannelCommandsResponse is the response type for the /channels/{platform}/{id}/commands endpoints.
+type ChannelCommandsResponse = common.GenericResponse[common.ChannelCommand]
+
+type reorderRequest struct {
+	Order []int `json:"order"`
+}
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands/{commandID:[0-9]+}",
+		Method:  http.MethodPatch,
+		Handler: patchChannelCommand,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands",
+		Method:  http.MethodPatch,
+		Handler: reorderChannelCommands,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+}
+
+func patchChannelCommand(writer http.ResponseWriter, request *http.Request) { //nolint:cyclop
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	commandID, err := strconv.Atoi(mux.Vars(request)["commandID"])
+	if err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid command ID"}},
+		}, start)
+
+		return
+	}
+
+	existing := []common.ChannelCommand{}
+	if channel.Commands != nil {
+		existing = *channel.Commands
+	}
+
+	var current *common.ChannelCommand
+	for i := range existing {
+		if existing[i].CommandID == commandID {
+			current = &existing[i]
+
+			break
+		}
+	}
+
+	if current == nil {
+		api.GenericResponse(writer, http.StatusNotFound, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Command not found"}},
+		}, start)
+
+		return
+	}
+
+	// Decode on top of the current command so omitted fields are left unchanged
+	command := *current
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&command); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	// Ownership and bookkeeping fields are not editable
+	command.CommandID = current.CommandID
+	command.ChannelID = current.ChannelID
+	command.Platform = current.Platform
+	command.UserID = current.UserID
+	command.UseCount = current.UseCount
+	command.Position = current.Position
+	command.Created = current.Created
+
+	if errs := api.ValidateChannelCommand(command, existing); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	if err = postgres.UpdateChannelCommand(request.Context(), &command); err != nil {
+		status, message := http.StatusInternalServerError, "Error updating command"
+		if errors.Is(err, db.ErrPostgresNoRows) {
+			status, message = http.StatusNotFound, "Command not found"
+		} else {
+			logger.Error.Printf("Error updating channel command: %v", err)
+		}
+
+		api.GenericResponse(writer, status, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.custom-command-update", api.ChannelCommandEvent{
+		ChannelID: channel.ChannelID,
+		Platform:  channel.Platform,
+		Action:    api.CommandUpdated,
+		Command:   &command,
+	})
+
+	api.GenericResponse(writer, http.StatusOK, ChannelCommandsResponse{
+		Data: &[]common.ChannelCommand{command},
+	}, start)
+}
+
+func reorderChannelCommands(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	var input reorderRequest
+	if err := json.NewDecoder(request.Body).Decode(&input); err != nil || len(input.Order) == 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	err := postgres.ReorderChannelCommands(request.Context(), channel.ChannelID, input.Order)
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Error reordering commands"
+		if errors.Is(err, db.ErrOrderMismatch) {
+			status, message = http.StatusBadRequest, "Order must contain every command exactly once"
+		} else {
+			logger.Error.Printf("Error reordering channel commands: %v", err)
+		}
+
+		api.GenericResponse(writer, status, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: message}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.custom-command-update", api.ChannelCommandEvent{
+		ChannelID: channel.ChannelID,
+		Platform:  channel.Platform,
+		Action:    api.CommandReordered,
+		Order:     input.Order,
+	})
+
+	commands := postgres.GetChannelCommands(request.Context(), channel.ChannelID)
+	if commands == nil {
+		commands = &[]common.ChannelCommand{}
+	}
+
+	api.GenericResponse(writer, http.StatusOK, ChannelCommandsResponse{
+		Data: commands,
+	}, start)
+}
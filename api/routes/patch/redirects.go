@@ -0,0 +1,114 @@
+// Package patch contains routes for http.MethodPatch requests.
+package patch
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// RedirectsResponse is the response type for the /redirects/{key} endpoint.
+type RedirectsResponse = common.GenericResponse[common.Redirect]
+
+type redirectPatch struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *int       `json:"max_clicks"`
+	URL       string     `json:"url"`
+	Preview   bool       `json:"preview"`
+}
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/redirects/{key}",
+		Method:  http.MethodPatch,
+		Handler: patchRedirect,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeRedirectsWrite},
+	})
+}
+
+func patchRedirect(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	redirect, ok := api.LoadManagedRedirect(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	// Decode on top of the current values so omitted fields are left unchanged
+	patch := redirectPatch{
+		URL:       redirect.URL,
+		ExpiresAt: redirect.ExpiresAt,
+		MaxClicks: redirect.MaxClicks,
+		Preview:   redirect.Preview,
+	}
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	var errs []common.ErrorMessage
+	if patch.URL != redirect.URL {
+		target, urlErrs, err := api.ValidateRedirectURL(request.Context(), postgres, request, patch.URL)
+		if err != nil {
+			logger.Error.Printf("Error validating redirect URL: %v", err)
+			api.GenericResponse(writer, http.StatusInternalServerError, RedirectsResponse{
+				Data:   &[]common.Redirect{},
+				Errors: &[]common.ErrorMessage{{Message: "Error validating URL"}},
+			}, start)
+
+			return
+		}
+
+		redirect.URL = target
+		errs = urlErrs
+	}
+
+	redirect.ExpiresAt = patch.ExpiresAt
+	redirect.MaxClicks = patch.MaxClicks
+	redirect.Preview = patch.Preview
+
+	if errs = append(errs, api.ValidateRedirectLimits(*redirect)...); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	if err := postgres.UpdateRedirect(request.Context(), redirect); err != nil {
+		logger.Error.Printf("Error updating redirect: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, RedirectsResponse{
+			Data:   &[]common.Redirect{},
+			Errors: &[]common.ErrorMessage{{Message: "Error updating redirect"}},
+		}, start)
+
+		return
+	}
+
+	api.UncacheRedirect(request.Context(), redirect.Key)
+
+	api.GenericResponse(writer, http.StatusOK, RedirectsResponse{
+		Data: &[]common.Redirect{*redirect},
+	}, start)
+}
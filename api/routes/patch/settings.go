@@ -0,0 +1,272 @@
+// Package patch contains routes for http.MethodPatch requests.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxPrefixLength = 15
+	maxCooldown     = 86400 // One day in seconds
+	maxListedUsers  = 500
+)
+
+// ChannelSettingsResponse is the response type for the /channels/{platform}/{id}/settings endpoint.
+type ChannelSettingsResponse = common.GenericResponse[common.ChannelSettings]
+
+// CommandSettingsResponse is the response type for the /channels/{platform}/{id}/commands/{command}/settings endpoint.
+type CommandSettingsResponse = common.GenericResponse[common.CommandSettings]
+
+type channelSettingsEvent struct {
+	Settings  common.ChannelSettings `json:"settings"`
+	ChannelID string                 `json:"channel_id"`
+	Platform  common.Platforms       `json:"platform"`
+}
+
+type commandSettingsEvent struct {
+	Settings common.CommandSettings `json:"settings"`
+	Platform common.Platforms       `json:"platform"`
+}
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/settings",
+		Method:  http.MethodPatch,
+		Handler: patchChannelSettings,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands/{command}/settings",
+		Method:  http.MethodPatch,
+		Handler: patchCommandSettings,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+}
+
+func validateCooldown(name string, cooldown int) []common.ErrorMessage {
+	if cooldown < 0 || cooldown > maxCooldown {
+		return []common.ErrorMessage{{
+			Message: fmt.Sprintf("%s must be between 0 and %d", name, maxCooldown),
+		}}
+	}
+
+	return nil
+}
+
+func validatePermission(permission string) []common.ErrorMessage {
+	if !common.UserRequires(permission).IsValid() {
+		return []common.ErrorMessage{{
+			Message: fmt.Sprintf("Invalid permission %q", permission),
+		}}
+	}
+
+	return nil
+}
+
+func validatePrefix(prefix string) []common.ErrorMessage {
+	switch {
+	case prefix == "":
+		return []common.ErrorMessage{{Message: "Prefix must not be empty"}}
+	case utf8.RuneCountInString(prefix) > maxPrefixLength:
+		return []common.ErrorMessage{{
+			Message: fmt.Sprintf("Prefix must be at most %d characters", maxPrefixLength),
+		}}
+	case strings.ContainsFunc(prefix, unicode.IsSpace):
+		return []common.ErrorMessage{{Message: "Prefix must not contain whitespace"}}
+	case strings.HasPrefix(prefix, "/") || strings.HasPrefix(prefix, "."):
+		return []common.ErrorMessage{{Message: "Prefix must not start with a chat command character"}}
+	default:
+		return nil
+	}
+}
+
+func validateUserList(name string, users []string) []common.ErrorMessage {
+	if len(users) > maxListedUsers {
+		return []common.ErrorMessage{{
+			Message: fmt.Sprintf("%s must contain at most %d users", name, maxListedUsers),
+		}}
+	}
+
+	return nil
+}
+
+func validateChannelSettings(settings common.ChannelSettings) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+
+	errs = append(errs, validatePrefix(settings.Prefix)...)
+	errs = append(errs, validatePermission(settings.Permission)...)
+	errs = append(errs, validateUserList("users_blacklisted", settings.UsersBlacklisted)...)
+
+	if settings.UserCooldown != nil {
+		errs = append(errs, validateCooldown("user_cooldown", *settings.UserCooldown)...)
+	}
+
+	if settings.ChannelCooldown != nil {
+		errs = append(errs, validateCooldown("channel_cooldown", *settings.ChannelCooldown)...)
+	}
+
+	return errs
+}
+
+func validateCommandSettings(settings common.CommandSettings) []common.ErrorMessage {
+	errs := make([]common.ErrorMessage, 0)
+
+	errs = append(errs, validatePermission(settings.Permission)...)
+	errs = append(errs, validateCooldown("custom_cooldown", settings.CustomCooldown)...)
+	errs = append(errs, validateUserList("users_blacklisted", settings.UsersBlacklisted)...)
+	errs = append(errs, validateUserList("users_whitelisted", settings.UsersWhitelisted)...)
+
+	return errs
+}
+
+func patchChannelSettings(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	// Decode on top of the current settings so omitted fields are left unchanged
+	settings := channel.Settings
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&settings); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelSettingsResponse{
+			Data:   &[]common.ChannelSettings{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	if errs := validateChannelSettings(settings); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelSettingsResponse{
+			Data:   &[]common.ChannelSettings{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	err := postgres.UpdateChannelSettings(request.Context(), channel.ChannelID, channel.Platform, settings)
+	if err != nil {
+		logger.Error.Printf("Error updating channel settings: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, ChannelSettingsResponse{
+			Data:   &[]common.ChannelSettings{},
+			Errors: &[]common.ErrorMessage{{Message: "Error updating channel settings"}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.channel-settings-update", channelSettingsEvent{
+		ChannelID: channel.ChannelID,
+		Platform:  channel.Platform,
+		Settings:  settings,
+	})
+
+	api.GenericResponse(writer, http.StatusOK, ChannelSettingsResponse{
+		Data: &[]common.ChannelSettings{settings},
+	}, start)
+}
+
+func patchCommandSettings(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	command := strings.ToLower(mux.Vars(request)["command"])
+
+	current, err := postgres.GetCommandSettings(request.Context(), channel.ChannelID, command)
+	if errors.Is(err, db.ErrPostgresNoRows) {
+		defaults := common.DefaultCommandSettings(channel.ChannelID, command)
+		current = &defaults
+	} else if err != nil {
+		logger.Error.Printf("Error fetching command settings: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, CommandSettingsResponse{
+			Data:   &[]common.CommandSettings{},
+			Errors: &[]common.ErrorMessage{{Message: "Error fetching command settings"}},
+		}, start)
+
+		return
+	}
+
+	settings := *current
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&settings); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, CommandSettingsResponse{
+			Data:   &[]common.CommandSettings{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	// Identity and usage are not editable
+	settings.ChannelID = current.ChannelID
+	settings.Command = current.Command
+	settings.ChannelUsage = current.ChannelUsage
+
+	if errs := validateCommandSettings(settings); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, CommandSettingsResponse{
+			Data:   &[]common.CommandSettings{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	if err = postgres.UpsertCommandSettings(request.Context(), settings); err != nil {
+		logger.Error.Printf("Error updating command settings: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, CommandSettingsResponse{
+			Data:   &[]common.CommandSettings{},
+			Errors: &[]common.ErrorMessage{{Message: "Error updating command settings"}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.command-settings-update", commandSettingsEvent{
+		Platform: channel.Platform,
+		Settings: settings,
+	})
+
+	api.GenericResponse(writer, http.StatusOK, CommandSettingsResponse{
+		Data: &[]common.CommandSettings{settings},
+	}, start)
+}
@@ -0,0 +1,140 @@
+// Package post contains routes for http.MethodPost requests.
+package post
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/utils"
+)
+
+// SessionResponse is the response type for the /auth/refresh endpoint.
+type SessionResponse = common.GenericResponse[middleware.TokenPair]
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/auth/refresh",
+		Method:  http.MethodPost,
+		Handler: refreshSession,
+		UseAuth: false,
+		RateLimit: &common.RateLimitPolicy{
+			KeyBy:  common.KeyByIP,
+			Limit:  10,
+			Window: 60,
+		},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/logout",
+		Method:  http.MethodPost,
+		Handler: logout,
+		UseAuth: true,
+	})
+	api.SetRoute(api.Route{
+		Path:    "/logout/all",
+		Method:  http.MethodPost,
+		Handler: logoutEverywhere,
+		UseAuth: true,
+	})
+}
+
+func newAuthenticator() *middleware.Authenticator {
+	config := utils.LoadConfig()
+
+	return middleware.NewAuthenticator(config.Twitch.ClientSecret, api.GenericResponse)
+}
+
+func refreshSession(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	var input refreshRequest
+	if err := json.NewDecoder(request.Body).Decode(&input); err != nil || input.RefreshToken == "" {
+		api.GenericResponse(writer, http.StatusBadRequest, SessionResponse{
+			Data:   &[]middleware.TokenPair{},
+			Errors: &[]common.ErrorMessage{{Message: "refresh_token is required"}},
+		}, start)
+
+		return
+	}
+
+	session, err := newAuthenticator().RefreshSession(request.Context(), input.RefreshToken)
+	if err != nil {
+		logger.Warn.Println("Failed to refresh session: ", err)
+		api.GenericResponse(writer, http.StatusUnauthorized, SessionResponse{
+			Data:   &[]middleware.TokenPair{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid refresh token"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, SessionResponse{
+		Data: &[]middleware.TokenPair{*session},
+	}, start)
+}
+
+func logout(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	if key, ok := request.Context().Value(middleware.AuthedKey).(*common.APIKey); ok && key != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, SessionResponse{
+			Data:   &[]middleware.TokenPair{},
+			Errors: &[]common.ErrorMessage{{Message: "API keys cannot be logged out, delete the key instead"}},
+		}, start)
+
+		return
+	}
+
+	token := strings.Replace(request.Header.Get("Authorization"), "Bearer ", "", 1)
+
+	if err := newAuthenticator().RevokeSession(request.Context(), token); err != nil {
+		logger.Error.Printf("Failed to revoke session: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, SessionResponse{
+			Data:   &[]middleware.TokenPair{},
+			Errors: &[]common.ErrorMessage{{Message: "Failed to log out"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, SessionResponse{
+		Data: &[]middleware.TokenPair{},
+	}, start)
+}
+
+func logoutEverywhere(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, SessionResponse{
+			Data:   &[]middleware.TokenPair{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	if err := newAuthenticator().RevokeAllSessions(request.Context(), user.ID); err != nil {
+		logger.Error.Printf("Failed to revoke sessions for user %d: %v", user.ID, err)
+		api.GenericResponse(writer, http.StatusInternalServerError, SessionResponse{
+			Data:   &[]middleware.TokenPair{},
+			Errors: &[]common.ErrorMessage{{Message: "Failed to log out"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusOK, SessionResponse{
+		Data: &[]middleware.TokenPair{},
+	}, start)
+}
@@ -0,0 +1,150 @@
+// Package post contains routes for http.MethodPost requests.
+package post
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// ChannelBlocksResponse is the response type for the /channels/{platform}/{id}/blocks endpoints.
+type ChannelBlocksResponse = common.GenericResponse[common.Block]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/blocks",
+		Method:  http.MethodPost,
+		Handler: createChannelBlock,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/blocks/import",
+		Method:  http.MethodPost,
+		Handler: importChannelBlocks,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+}
+
+func createChannelBlock(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	var block common.Block
+	if err := json.NewDecoder(request.Body).Decode(&block); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	addChannelBlocks(writer, request, start, []common.Block{block})
+}
+
+func importChannelBlocks(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	var blocks []common.Block
+	if err := json.NewDecoder(request.Body).Decode(&blocks); err != nil || len(blocks) == 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	if len(blocks) > api.MaxBlockImport {
+		api.GenericResponse(writer, http.StatusRequestEntityTooLarge, ChannelBlocksResponse{
+			Data: &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{
+				Message: fmt.Sprintf("Too many blocks provided. Expected 1-%d, found %d", api.MaxBlockImport, len(blocks)),
+			}},
+		}, start)
+
+		return
+	}
+
+	addChannelBlocks(writer, request, start, blocks)
+}
+
+func addChannelBlocks(
+	writer http.ResponseWriter,
+	request *http.Request,
+	start time.Time,
+	blocks []common.Block,
+) {
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	errs := make([]common.ErrorMessage, 0)
+	for i := range blocks {
+		blocks[i].BlockType = common.BlockType(strings.ToUpper(string(blocks[i].BlockType)))
+		blocks[i].ChannelID = channel.ChannelID
+		blocks[i].ID = user.ID
+		if blocks[i].BlockType == common.CommandBlock {
+			blocks[i].CommandName = strings.ToLower(strings.TrimSpace(blocks[i].CommandName))
+		} else {
+			blocks[i].CommandName = ""
+		}
+
+		errs = append(errs, api.ValidateBlock(blocks[i], user)...)
+	}
+
+	if len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	added, err := postgres.AddChannelBlocks(request.Context(), blocks)
+	if err != nil {
+		logger.Error.Printf("Error adding channel blocks: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, ChannelBlocksResponse{
+			Data:   &[]common.Block{},
+			Errors: &[]common.ErrorMessage{{Message: "Error adding blocks"}},
+		}, start)
+
+		return
+	}
+
+	if added > 0 {
+		api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.channel-blocks-update", api.ChannelBlockEvent{
+			ChannelID: channel.ChannelID,
+			Platform:  channel.Platform,
+			Action:    api.BlocksAdded,
+			Blocks:    blocks,
+		})
+	}
+
+	api.GenericResponse(writer, http.StatusCreated, ChannelBlocksResponse{
+		Data: &blocks,
+	}, start)
+}
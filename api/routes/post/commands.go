@@ -0,0 +1,103 @@
+// Package post contains routes for http.MethodPost requests.
+package post
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// ChannelCommandsResponse is the response type for the /channels/{platform}/{id}/commands endpoint.
+type ChannelCommandsResponse = common.GenericResponse[common.ChannelCommand]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/channels/{platform}/{id}/commands",
+		Method:  http.MethodPost,
+		Handler: createChannelCommand,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeChannelsWrite},
+	})
+}
+
+func createChannelCommand(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	channel, ok := api.LoadManagedChannel(writer, request, start)
+	if !ok {
+		return
+	}
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok {
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	command := api.NewChannelCommand(channel, user.ID)
+	decoder := json.NewDecoder(request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&command); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	// Ownership and bookkeeping fields are always set by the server
+	defaults := api.NewChannelCommand(channel, user.ID)
+	command.CommandID = 0
+	command.ChannelID = defaults.ChannelID
+	command.Platform = defaults.Platform
+	command.UserID = defaults.UserID
+	command.UseCount = 0
+
+	existing := []common.ChannelCommand{}
+	if channel.Commands != nil {
+		existing = *channel.Commands
+	}
+
+	if errs := api.ValidateChannelCommand(command, existing); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	if err := postgres.CreateChannelCommand(request.Context(), &command); err != nil {
+		logger.Error.Printf("Error creating channel command: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, ChannelCommandsResponse{
+			Data:   &[]common.ChannelCommand{},
+			Errors: &[]common.ErrorMessage{{Message: "Error creating command"}},
+		}, start)
+
+		return
+	}
+
+	api.PublishEvent(request.Context(), "github.com/Potat-Industries/potat-api.custom-command-update", api.ChannelCommandEvent{
+		ChannelID: channel.ChannelID,
+		Platform:  channel.Platform,
+		Action:    api.CommandCreated,
+		Command:   &command,
+	})
+
+	api.GenericResponse(writer, http.StatusCreated, ChannelCommandsResponse{
+		Data: &[]common.ChannelCommand{command},
+	}, start)
+}
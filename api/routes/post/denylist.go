@@ -0,0 +1,103 @@
+// Package post contains routes for http.MethodPost requests.
+package post
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// DenylistResponse is the response type for the /redirects/denylist endpoints.
+type DenylistResponse = common.GenericResponse[common.DeniedDomain]
+
+type denyDomainRequest struct {
+	Reason *string `json:"reason"`
+	Domain string  `json:"domain"`
+}
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:     "/redirects/denylist",
+		Method:   http.MethodPost,
+		Handler:  denyDomain,
+		MinLevel: common.ADMIN,
+		Scopes:   []string{api.ScopeRedirectsWrite},
+	})
+}
+
+func denyDomain(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	var input denyDomainRequest
+	if err := json.NewDecoder(request.Body).Decode(&input); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	domain, errs := api.NormalizeDeniedDomain(input.Domain)
+	if len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	denied := common.DeniedDomain{
+		Domain:  domain,
+		Reason:  input.Reason,
+		AddedBy: &user.ID,
+	}
+
+	added, err := postgres.AddDeniedDomain(request.Context(), &denied)
+	if err != nil {
+		logger.Error.Printf("Error adding domain to redirect denylist: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Error updating denylist"}},
+		}, start)
+
+		return
+	}
+
+	if !added {
+		api.GenericResponse(writer, http.StatusConflict, DenylistResponse{
+			Data:   &[]common.DeniedDomain{},
+			Errors: &[]common.ErrorMessage{{Message: "Domain is already denied"}},
+		}, start)
+
+		return
+	}
+
+	api.GenericResponse(writer, http.StatusCreated, DenylistResponse{
+		Data: &[]common.DeniedDomain{denied},
+	}, start)
+}
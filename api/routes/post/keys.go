@@ -0,0 +1,149 @@
+// Package post contains routes for http.MethodPost requests.
+package post
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// APIKeysResponse is the response type for the /twitch/me/keys endpoint.
+type APIKeysResponse = common.GenericResponse[common.APIKey]
+
+type createAPIKeyRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+}
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/twitch/me/keys",
+		Method:  http.MethodPost,
+		Handler: createAPIKey,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeKeysManage},
+		RateLimit: &common.RateLimitPolicy{
+			KeyBy:  common.KeyByUser,
+			Limit:  10,
+			Window: 3600,
+		},
+	})
+}
+
+func createAPIKey(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	var input createAPIKeyRequest
+	if err := json.NewDecoder(request.Body).Decode(&input); err != nil {
+		api.GenericResponse(writer, http.StatusBadRequest, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	key := common.APIKey{
+		UserID:    user.ID,
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+
+	if errs := api.ValidateAPIKey(key); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	caller, _ := request.Context().Value(middleware.AuthedKey).(*common.APIKey)
+	if errs := api.CheckAPIKeyGrant(key, caller); len(errs) > 0 {
+		api.GenericResponse(writer, http.StatusForbidden, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &errs,
+		}, start)
+
+		return
+	}
+
+	count, err := postgres.CountAPIKeys(request.Context(), user.ID)
+	if err != nil {
+		logger.Error.Printf("Error counting API keys: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Error creating API key"}},
+		}, start)
+
+		return
+	}
+
+	if count >= api.MaxAPIKeys {
+		api.GenericResponse(writer, http.StatusConflict, APIKeysResponse{
+			Data: &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{
+				Message: fmt.Sprintf("You can not have more than %d API keys", api.MaxAPIKeys),
+			}},
+		}, start)
+
+		return
+	}
+
+	token, prefix, hash, err := middleware.GenerateAPIKey()
+	if err != nil {
+		logger.Error.Printf("Error generating API key: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Error creating API key"}},
+		}, start)
+
+		return
+	}
+
+	key.Prefix = prefix
+	if err = postgres.CreateAPIKey(request.Context(), &key, hash); err != nil {
+		logger.Error.Printf("Error creating API key: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, APIKeysResponse{
+			Data:   &[]common.APIKey{},
+			Errors: &[]common.ErrorMessage{{Message: "Error creating API key"}},
+		}, start)
+
+		return
+	}
+
+	key.Key = token
+
+	api.GenericResponse(writer, http.StatusCreated, APIKeysResponse{
+		Data: &[]common.APIKey{key},
+	}, start)
+}
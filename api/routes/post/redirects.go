@@ -2,18 +2,16 @@
 package post
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/Potat-Industries/potat-api/api"
 	"github.com/Potat-Industries/potat-api/api/middleware"
 	"github.com/Potat-Industries/potat-api/common"
 	"github.com/Potat-Industries/potat-api/common/db"
 	"github.com/Potat-Industries/potat-api/common/logger"
-	"github.com/Potat-Industries/potat-api/common/utils"
 )
 
 func init() {
@@ -25,19 +23,36 @@ func init() {
 	})
 }
 
-func createRedirect(writer http.ResponseWriter, request *http.Request) { //nolint:cyclop
-	var input common.Redirect
+type createRedirectRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *int       `json:"max_clicks"`
+	URL       string     `json:"url"`
+	Alias     string     `json:"alias"`
+	Preview   bool       `json:"preview"`
+}
+
+// RedirectsResponse is the response type for rejected /redirect requests, successful
+// requests respond with the plain short link.
+type RedirectsResponse = common.GenericResponse[common.Redirect]
+
+func writeRedirectError(writer http.ResponseWriter, status int, errs []common.ErrorMessage, start time.Time) {
+	api.GenericResponse(writer, status, RedirectsResponse{
+		Data:   &[]common.Redirect{},
+		Errors: &errs,
+	}, start)
+}
+
+func createRedirect(writer http.ResponseWriter, request *http.Request) { //nolint:cyclop,funlen
+	start := time.Now()
+
+	var input createRedirectRequest
 	if err := json.NewDecoder(request.Body).Decode(&input); err != nil {
 		logger.Error.Printf("Invalid request body: %v", err)
-		http.Error(writer, "Bad Request", http.StatusBadRequest)
+		writeRedirectError(writer, http.StatusBadRequest, []common.ErrorMessage{{Message: "Invalid request body"}}, start)
 
 		return
 	}
 
-	if !strings.HasPrefix(input.URL, "http://") && !strings.HasPrefix(input.URL, "https://") {
-		input.URL = "https://" + input.URL
-	}
-
 	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
 	if !ok {
 		logger.Error.Println("Postgres client not found in context")
@@ -45,53 +60,85 @@ func createRedirect(writer http.ResponseWriter, request *http.Request) { //nolin
 		return
 	}
 
-	key, err := postgres.GetKeyByRedirect(request.Context(), input.URL)
-	if err == nil && key != "" {
-		response := fmt.Sprintf("https://%s/%s", request.Host, key)
-		_, err = writer.Write([]byte(response))
-		if err != nil {
-			logger.Error.Printf("Failed to write response: %v", err)
-		}
+	target, errs, err := api.ValidateRedirectURL(request.Context(), postgres, request, input.URL)
+	if err != nil {
+		logger.Error.Printf("Error validating redirect URL: %v", err)
+		writeRedirectError(writer, http.StatusInternalServerError, []common.ErrorMessage{{
+			Message: "Error validating URL",
+		}}, start)
 
 		return
 	}
 
-	key, err = generateUniqueKey(request.Context())
-	if err != nil {
-		logger.Error.Printf("Error generating key: %v", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
-
-		return
+	redirect := common.Redirect{
+		URL:       target,
+		ExpiresAt: input.ExpiresAt,
+		MaxClicks: input.MaxClicks,
+		Custom:    input.Alias != "",
+		Preview:   input.Preview,
+	}
+	if user, ok := request.Context().Value(middleware.AuthedUser).(*common.User); ok && user != nil {
+		redirect.OwnerID = &user.ID
 	}
 
-	if err = postgres.NewRedirect(request.Context(), key, input.URL); err != nil {
-		logger.Error.Printf("Error inserting redirect: %v", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+	errs = append(errs, api.ValidateRedirectLimits(redirect)...)
+	if redirect.Custom {
+		errs = append(errs, api.ValidateRedirectAlias(input.Alias)...)
+	}
+	if len(errs) > 0 {
+		writeRedirectError(writer, http.StatusBadRequest, errs, start)
 
 		return
 	}
 
-	response := fmt.Sprintf("https://%s/%s", request.Host, key)
-	if _, err = writer.Write([]byte(response)); err != nil {
-		logger.Error.Printf("Failed to write response: %v", err)
+	plain := !redirect.Custom && !redirect.Preview &&
+		redirect.OwnerID == nil && redirect.ExpiresAt == nil && redirect.MaxClicks == nil
+	if plain {
+		key, err := postgres.GetKeyByRedirect(request.Context(), redirect.URL)
+		if err == nil && key != "" {
+			response := fmt.Sprintf("https://%s/%s", request.Host, key)
+			_, err = writer.Write([]byte(response))
+			if err != nil {
+				logger.Error.Printf("Failed to write response: %v", err)
+			}
+
+			return
+		}
 	}
-}
 
-func generateUniqueKey(ctx context.Context) (string, error) {
-	postgres, ok := ctx.Value(middleware.PostgresKey).(*db.PostgresClient)
-	if !ok {
-		logger.Error.Println("Postgres client not found in context")
+	if !redirect.Custom {
+		if err := api.CreateGeneratedRedirect(request.Context(), postgres, &redirect); err != nil {
+			logger.Error.Printf("Error inserting redirect: %v", err)
+			writeRedirectError(writer, http.StatusInternalServerError, []common.ErrorMessage{{
+				Message: "Error creating redirect",
+			}}, start)
 
-		return "", middleware.ErrMissingContext
-	}
+			return
+		}
+	} else {
+		redirect.Key = input.Alias
 
-	for {
-		key, err := utils.RandomString(6)
+		created, err := postgres.CreateRedirect(request.Context(), &redirect)
 		if err != nil {
-			return "", err
+			logger.Error.Printf("Error inserting redirect: %v", err)
+			writeRedirectError(writer, http.StatusInternalServerError, []common.ErrorMessage{{
+				Message: "Error creating redirect",
+			}}, start)
+
+			return
 		}
-		if postgres.RedirectExists(ctx, key) {
-			return key, nil
+
+		if !created {
+			writeRedirectError(writer, http.StatusConflict, []common.ErrorMessage{{
+				Message: "Alias is already taken",
+			}}, start)
+
+			return
 		}
 	}
+
+	response := fmt.Sprintf("https://%s/%s", request.Host, redirect.Key)
+	if _, err := writer.Write([]byte(response)); err != nil {
+		logger.Error.Printf("Failed to write response: %v", err)
+	}
 }
@@ -0,0 +1,91 @@
+package post
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/api"
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+// DeletedUploadsResponse is the response type for the /twitch/me/uploads/delete endpoint.
+type DeletedUploadsResponse = common.GenericResponse[string]
+
+func init() {
+	api.SetRoute(api.Route{
+		Path:    "/twitch/me/uploads/delete",
+		Method:  http.MethodPost,
+		Handler: deleteOwnedUploads,
+		UseAuth: true,
+		Scopes:  []string{api.ScopeUploadsWrite},
+	})
+}
+
+func deleteOwnedUploads(writer http.ResponseWriter, request *http.Request) {
+	start := time.Now()
+
+	user, ok := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if !ok || user == nil {
+		api.GenericResponse(writer, http.StatusUnauthorized, DeletedUploadsResponse{
+			Data:   &[]string{},
+			Errors: &[]common.ErrorMessage{{Message: "Unauthorized"}},
+		}, start)
+
+		return
+	}
+
+	postgres, ok := request.Context().Value(middleware.PostgresKey).(*db.PostgresClient)
+	if !ok {
+		logger.Error.Println("Postgres client not found in context")
+
+		return
+	}
+
+	var keys []string
+	if err := json.NewDecoder(request.Body).Decode(&keys); err != nil || len(keys) == 0 {
+		api.GenericResponse(writer, http.StatusBadRequest, DeletedUploadsResponse{
+			Data:   &[]string{},
+			Errors: &[]common.ErrorMessage{{Message: "Invalid request body"}},
+		}, start)
+
+		return
+	}
+
+	if len(keys) > api.MaxUploadBulkDelete {
+		api.GenericResponse(writer, http.StatusRequestEntityTooLarge, DeletedUploadsResponse{
+			Data: &[]string{},
+			Errors: &[]common.ErrorMessage{{
+				Message: fmt.Sprintf("Too many uploads provided. Expected 1-%d, found %d", api.MaxUploadBulkDelete, len(keys)),
+			}},
+		}, start)
+
+		return
+	}
+
+	// Uploads of other users are silently skipped, only the deleted keys are returned.
+	deleted, blobKeys, err := postgres.DeleteOwnedUploads(request.Context(), user.ID, keys)
+	if err != nil {
+		logger.Error.Printf("Error deleting uploads: %v", err)
+		api.GenericResponse(writer, http.StatusInternalServerError, DeletedUploadsResponse{
+			Data:   &[]string{},
+			Errors: &[]common.ErrorMessage{{Message: "Error deleting uploads"}},
+		}, start)
+
+		return
+	}
+
+	api.DeleteUploadBlobs(request.Context(), blobKeys)
+
+	if deleted == nil {
+		deleted = []string{}
+	}
+
+	api.GenericResponse(writer, http.StatusOK, DeletedUploadsResponse{
+		Data: &deleted,
+	}, start)
+}
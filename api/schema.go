@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Potat-Industries/potat-api/common/db"
+)
+
+// Mirrors the columns the redirects server adds to url_redirects, for deployments
+// running the API without the redirects server.
+const alterRedirects = `
+	ALTER TABLE IF EXISTS url_redirects
+		ALTER COLUMN key TYPE VARCHAR(32),
+		ADD COLUMN IF NOT EXISTS owner_id INT,
+		ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP,
+		ADD COLUMN IF NOT EXISTS max_clicks INT,
+		ADD COLUMN IF NOT EXISTS clicks INT DEFAULT 0 NOT NULL,
+		ADD COLUMN IF NOT EXISTS custom BOOLEAN DEFAULT FALSE NOT NULL,
+		ADD COLUMN IF NOT EXISTS preview BOOLEAN DEFAULT FALSE NOT NULL,
+		ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT NOW() NOT NULL;
+`
+
+// Mirrors the owner column the uploader adds to file_store, for deployments running the API
+// without the uploader.
+const alterUploads = `
+	ALTER TABLE IF EXISTS file_store
+		ADD COLUMN IF NOT EXISTS owner_id INT;
+`
+
+const createRedirectDenylist = `
+	CREATE TABLE IF NOT EXISTS redirect_denylist (
+		domain VARCHAR(253) PRIMARY KEY,
+		reason TEXT,
+		added_by INT,
+		created_at TIMESTAMP DEFAULT NOW() NOT NULL
+	);
+`
+
+const createAPIKeys = `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		key_id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL,
+		name VARCHAR(64) NOT NULL,
+		prefix VARCHAR(16) NOT NULL,
+		key_hash CHAR(64) NOT NULL UNIQUE,
+		scopes TEXT[] NOT NULL DEFAULT '{}',
+		expires_at TIMESTAMP,
+		last_used TIMESTAMP,
+		created TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+`
+
+func migrate(ctx context.Context, postgres *db.PostgresClient) {
+	postgres.CheckTableExists(ctx, createAPIKeys)
+	postgres.CheckTableExists(ctx, alterRedirects)
+	postgres.CheckTableExists(ctx, alterUploads)
+	postgres.CheckTableExists(ctx, createRedirectDenylist)
+}
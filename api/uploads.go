@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+
+	"github.com/Potat-Industries/potat-api/api/middleware"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
+)
+
+// MaxUploadBulkDelete is the maximum number of uploads deleted in a single request.
+const MaxUploadBulkDelete = 100
+
+// DeleteUploadBlobs removes blobs no upload references anymore from upload storage.
+func DeleteUploadBlobs(ctx context.Context, blobKeys []string) {
+	store, ok := ctx.Value(middleware.StorageKey).(storage.Store)
+	if !ok {
+		logger.Error.Println("Upload storage not found in context")
+
+		return
+	}
+
+	for _, blobKey := range blobKeys {
+		if err := storage.DeleteBlob(ctx, store, blobKey); err != nil {
+			logger.Error.Printf("Error deleting blob %s from storage: %v", blobKey, err)
+		}
+	}
+}
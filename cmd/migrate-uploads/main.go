@@ -0,0 +1,130 @@
+// Package main moves uploaded files stored in the Postgres file_store table into the configured
+// blob storage, leaving only their metadata in Postgres.
+//
+// It is safe to stop and run again, files are only cleared from Postgres once they are stored.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
+	"github.com/Potat-Industries/potat-api/common/utils"
+)
+
+const (
+	batchSize     = 100
+	blobKeyLength = 32
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	config := utils.LoadConfig()
+
+	postgres, err := db.InitPostgres(ctx, *config)
+	if err != nil {
+		logger.Error.Panicln("Failed initializing Postgres", err)
+	}
+	defer postgres.Close()
+
+	store, err := storage.New(config.Storage)
+	if err != nil {
+		logger.Error.Panicln("Failed initializing upload storage", err)
+	}
+
+	migrated, failed := 0, map[string]bool{}
+	for ctx.Err() == nil {
+		keys, err := postgres.ListLegacyUploads(ctx, batchSize+len(failed))
+		if err != nil {
+			logger.Error.Panicln("Failed listing uploads to migrate", err)
+		}
+
+		pending := 0
+		for _, key := range keys {
+			if failed[key] {
+				continue
+			}
+			pending++
+
+			if err = migrate(ctx, postgres, store, key); err != nil {
+				logger.Warn.Printf("Failed migrating upload %s: %v", key, err)
+				failed[key] = true
+
+				continue
+			}
+			migrated++
+		}
+
+		if pending == 0 {
+			break
+		}
+
+		logger.Info.Printf("Migrated %d uploads", migrated)
+	}
+
+	logger.Info.Printf("Migration finished, %d uploads migrated and %d failed", migrated, len(failed))
+}
+
+// migrate stores a file as a blob, sharing an existing blob if identical content was already
+// uploaded, then drops its contents from Postgres.
+func migrate(ctx context.Context, postgres *db.PostgresClient, store storage.Store, key string) error {
+	upload, err := postgres.GetUpload(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	data, err := postgres.GetLegacyUploadFile(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	blobKey, err := utils.RandomKey(utils.AlphabetBase62, blobKeyLength)
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(data))
+	if err = store.Put(ctx, blobKey, bytes.NewReader(data), size, upload.MimeType); err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+
+	claimed, err := postgres.ClaimUploadBlob(ctx, sum, blobKey, size)
+	if err != nil {
+		return errors.Join(err, store.Delete(ctx, blobKey))
+	}
+	if claimed != blobKey {
+		if err = store.Delete(ctx, blobKey); err != nil {
+			logger.Warn.Printf("Failed deleting duplicate blob %s: %v", blobKey, err)
+		}
+	}
+
+	if err = postgres.ClearLegacyUploadFile(ctx, key, size, sum, claimed); err != nil {
+		if released, releaseErr := postgres.ReleaseUploadBlob(ctx, sum); releaseErr == nil && released != "" {
+			err = errors.Join(err, store.Delete(ctx, released))
+		}
+
+		return err
+	}
+
+	// Thumbnails used to be stored under the upload's own key before it had a blob.
+	for _, width := range storage.ThumbnailWidths {
+		if err = store.Delete(ctx, storage.ThumbnailKey(key, width)); err != nil {
+			logger.Warn.Printf("Failed deleting legacy thumbnail of %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
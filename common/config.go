@@ -3,18 +3,20 @@ package common
 
 // Config holds the configuration for the application, including database and service settings.
 type Config struct {
-	Postgres   SQLConfig    `json:"postgres"`
-	Clickhouse SQLConfig    `json:"clickhouse"`
-	Twitch     TwitchConfig `json:"twitch"`
-	Redis      RedisConfig  `json:"redis"`
-	API        APIConfig    `json:"api"`
-	Socket     APIConfig    `json:"socket"`
-	Redirects  APIConfig    `json:"redirects"`
-	Uploader   APIConfig    `json:"uploader"`
-	Prometheus APIConfig    `json:"prometheus"`
-	Haste      HasteConfig  `json:"haste"`
-	Nats       BoolConfig   `json:"nats"`
-	Loops      BoolConfig   `json:"loops"`
+	Postgres   SQLConfig       `json:"postgres"`
+	Clickhouse SQLConfig       `json:"clickhouse"`
+	Twitch     TwitchConfig    `json:"twitch"`
+	Redis      RedisConfig     `json:"redis"`
+	API        APIConfig       `json:"api"`
+	Socket     APIConfig       `json:"socket"`
+	Redirects  APIConfig       `json:"redirects"`
+	Uploader   APIConfig       `json:"uploader"`
+	Prometheus APIConfig       `json:"prometheus"`
+	Haste      HasteConfig     `json:"haste"`
+	Storage    StorageConfig   `json:"storage"`
+	Nats       BoolConfig      `json:"nats"`
+	Loops      BoolConfig      `json:"loops"`
+	RateLimits RateLimitConfig `json:"rate_limits"`
 }
 
 // TwitchConfig holds the configuration for Twitch API integration.
@@ -30,20 +32,38 @@ type BoolConfig struct {
 }
 
 // APIConfig holds the configuration for various API services, including host, port, and authentication settings.
+// PublicHost is the host the service is reached at from the internet, if it differs from Host.
 type APIConfig struct {
-	Host    string `json:"host"`
-	Port    string `json:"port"`
-	AuthKey string `json:"authkey,omitempty"`
-	Enabled bool   `json:"enabled"`
+	Host       string `json:"host"`
+	Port       string `json:"port"`
+	PublicHost string `json:"public_host,omitempty"`
+	AuthKey    string `json:"authkey,omitempty"`
+	Enabled    bool   `json:"enabled"`
 }
 
 // HasteConfig holds the configuration for the Hastebin service, including host, port, key length,
-// and whether it is enabled.
+// key alphabet ("base62" or "hex"), maximum document size in bytes, and whether it is enabled.
 type HasteConfig struct {
-	Host      string `json:"host"`
-	Port      string `json:"port"`
-	KeyLength int    `json:"keyLength"`
-	Enabled   bool   `json:"enabled"`
+	Host        string `json:"host"`
+	Port        string `json:"port"`
+	KeyAlphabet string `json:"keyAlphabet"`
+	KeyLength   int    `json:"keyLength"`
+	MaxSize     int64  `json:"maxSize"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// StorageConfig selects where uploaded files are stored. Backend is "local" to keep files in the
+// directory at Path, or "s3" for a bucket on AWS S3 or an S3 compatible Endpoint such as MinIO,
+// which usually needs PathStyle requests.
+type StorageConfig struct {
+	Backend   string `json:"backend"`
+	Path      string `json:"path,omitempty"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	AccessKey string `json:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	PathStyle bool   `json:"path_style,omitempty"`
 }
 
 // SQLConfig holds the configuration for SQL databases, including host, port, user, password, and database name.
@@ -60,3 +80,40 @@ type RedisConfig struct {
 	Host string `json:"host"`
 	Port string `json:"port"`
 }
+
+// RateLimitAlgorithm is the algorithm a rate limit policy counts requests with.
+type RateLimitAlgorithm string
+
+//nolint:revive
+const (
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+	TokenBucket   RateLimitAlgorithm = "token_bucket"
+	FixedWindow   RateLimitAlgorithm = "fixed_window"
+)
+
+// RateLimitKey is the identity requests are grouped by when rate limiting.
+type RateLimitKey string
+
+//nolint:revive
+const (
+	KeyByIP       RateLimitKey = "ip"
+	KeyByUser     RateLimitKey = "user"
+	KeyByAPIKey   RateLimitKey = "api_key"
+	KeyByIdentity RateLimitKey = "identity"
+)
+
+// RateLimitPolicy holds a single rate limit, allowing Limit requests per Window seconds.
+// Unset fields fall back to the defaults of the server or route the policy overrides.
+type RateLimitPolicy struct {
+	Algorithm RateLimitAlgorithm `json:"algorithm,omitempty"`
+	KeyBy     RateLimitKey       `json:"key_by,omitempty"`
+	Limit     int64              `json:"limit,omitempty"`
+	Window    int                `json:"window,omitempty"`
+}
+
+// RateLimitConfig holds rate limit policy overrides and the proxies trusted to forward client addresses.
+// Policies are keyed by server and policy name, e.g. "api.default", "uploader.delete" or "api.POST /redirect".
+type RateLimitConfig struct {
+	Policies       map[string]RateLimitPolicy `json:"policies"`
+	TrustedProxies []string                   `json:"trusted_proxies"`
+}
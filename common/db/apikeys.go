@@ -0,0 +1,118 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// CountAPIKeys returns how many API keys a user currently has.
+func (db *PostgresClient) CountAPIKeys(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := db.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM api_keys WHERE user_id = $1`, userID).Scan(&count)
+
+	return count, err
+}
+
+// CreateAPIKey stores a new API key by its hash, filling in its ID and creation time.
+func (db *PostgresClient) CreateAPIKey(ctx context.Context, key *common.APIKey, hash string) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING key_id, created;
+	`
+
+	return db.Pool.QueryRow(
+		ctx,
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		hash,
+		key.Scopes,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.Created)
+}
+
+// ListAPIKeys retrieves every API key belonging to a user, newest first.
+func (db *PostgresClient) ListAPIKeys(ctx context.Context, userID int) ([]common.APIKey, error) {
+	query := `
+		SELECT key_id, user_id, name, prefix, scopes, expires_at, last_used, created
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created DESC;
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := make([]common.APIKey, 0)
+	for rows.Next() {
+		var key common.APIKey
+		err = rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			&key.Scopes,
+			&key.ExpiresAt,
+			&key.LastUsed,
+			&key.Created,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey revokes one of a user's API keys, returning false if it did not exist.
+func (db *PostgresClient) DeleteAPIKey(ctx context.Context, userID, keyID int) (bool, error) {
+	tag, err := db.Pool.Exec(
+		ctx,
+		`DELETE FROM api_keys WHERE key_id = $1 AND user_id = $2`,
+		keyID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseAPIKey looks up an unexpired API key by its hash and records it as used.
+// Returns pgx.ErrNoRows if no such key exists.
+func (db *PostgresClient) UseAPIKey(ctx context.Context, hash string) (*common.APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET last_used = NOW()
+		WHERE key_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING key_id, user_id, name, prefix, scopes, expires_at, last_used, created;
+	`
+
+	var key common.APIKey
+	err := db.Pool.QueryRow(ctx, query, hash).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsed,
+		&key.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
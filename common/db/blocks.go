@@ -0,0 +1,127 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// ListChannelBlocks retrieves a page of blocks for a channel, optionally filtered by type,
+// along with the total number of matching blocks.
+func (db *PostgresClient) ListChannelBlocks(
+	ctx context.Context,
+	channelID string,
+	blockType common.BlockType,
+	limit int,
+	offset int,
+) ([]common.Block, int, error) {
+	query := `
+		SELECT
+			user_id,
+			COALESCE(block_id, 0),
+			channel_id,
+			block_type,
+			COALESCE(block_data, ''),
+			COUNT(*) OVER () AS total
+		FROM blocks
+		WHERE channel_id = $1 AND ($2::TEXT = '' OR block_type::TEXT = $2::TEXT)
+		ORDER BY block_type, block_id, block_data
+		LIMIT $3 OFFSET $4;
+	`
+
+	rows, err := db.Pool.Query(ctx, query, channelID, string(blockType), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	total := 0
+	blocks := make([]common.Block, 0)
+	for rows.Next() {
+		var block common.Block
+		err = rows.Scan(
+			&block.ID,
+			&block.BlockedUserID,
+			&block.ChannelID,
+			&block.BlockType,
+			&block.CommandName,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, total, rows.Err()
+}
+
+// AddChannelBlocks inserts blocks that do not already exist, returning how many were added.
+func (db *PostgresClient) AddChannelBlocks(ctx context.Context, blocks []common.Block) (int, error) {
+	query := `
+		INSERT INTO blocks (user_id, block_id, channel_id, block_type, block_data)
+		SELECT DISTINCT
+			b.user_id,
+			NULLIF(b.block_id, 0),
+			b.channel_id,
+			b.block_type,
+			NULLIF(b.block_data, '')
+		FROM unnest($1::INT[], $2::INT[], $3::TEXT[], $4::TEXT[], $5::TEXT[])
+			AS b(user_id, block_id, channel_id, block_type, block_data)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM blocks e
+			WHERE e.channel_id = b.channel_id
+			AND e.block_type::TEXT = b.block_type
+			AND COALESCE(e.block_id, 0) = b.block_id
+			AND COALESCE(e.block_data, '') = b.block_data
+		);
+	`
+
+	userIDs := make([]int, len(blocks))
+	blockedIDs := make([]int, len(blocks))
+	channelIDs := make([]string, len(blocks))
+	blockTypes := make([]string, len(blocks))
+	commands := make([]string, len(blocks))
+	for i, block := range blocks {
+		userIDs[i] = block.ID
+		blockedIDs[i] = block.BlockedUserID
+		channelIDs[i] = block.ChannelID
+		blockTypes[i] = string(block.BlockType)
+		commands[i] = block.CommandName
+	}
+
+	tag, err := db.Pool.Exec(ctx, query, userIDs, blockedIDs, channelIDs, blockTypes, commands)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// RemoveChannelBlock deletes a block from a channel, returning false if it did not exist.
+func (db *PostgresClient) RemoveChannelBlock(ctx context.Context, block common.Block) (bool, error) {
+	query := `
+		DELETE FROM blocks
+		WHERE channel_id = $1
+		AND block_type = $2
+		AND COALESCE(block_id, 0) = $3
+		AND COALESCE(block_data, '') = $4;
+	`
+
+	tag, err := db.Pool.Exec(
+		ctx,
+		query,
+		block.ChannelID,
+		block.BlockType,
+		block.BlockedUserID,
+		block.CommandName,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,168 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+const createRedirectClicks = `
+	CREATE TABLE IF NOT EXISTS potatbotat.redirect_clicks (
+		timestamp DateTime64(3) CODEC(Delta, ZSTD),
+		key String,
+		referrer_host LowCardinality(String),
+		country LowCardinality(String),
+		user_agent_class LowCardinality(String),
+		cache_hit Bool
+	)
+	ENGINE = MergeTree
+	PARTITION BY toYYYYMM(timestamp)
+	ORDER BY (key, timestamp)
+	TTL toDateTime(timestamp) + INTERVAL 1 YEAR;
+`
+
+const statsTopLimit = 10
+
+// EnsureRedirectClicks creates the redirect click analytics table if it does not exist.
+func (c *ClickhouseClient) EnsureRedirectClicks(ctx context.Context) error {
+	return c.Exec(ctx, createRedirectClicks)
+}
+
+// InsertRedirectClicks writes a batch of redirect clicks.
+func (c *ClickhouseClient) InsertRedirectClicks(ctx context.Context, clicks []common.RedirectClick) error {
+	batch, err := c.PrepareBatch(ctx, `INSERT INTO potatbotat.redirect_clicks`)
+	if err != nil {
+		return err
+	}
+
+	for _, click := range clicks {
+		err = batch.Append(
+			click.Timestamp,
+			click.Key,
+			click.ReferrerHost,
+			click.Country,
+			click.UserAgentClass,
+			click.CacheHit,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return batch.Send()
+}
+
+// CountRedirectClicks returns how many times a redirect has been followed.
+func (c *ClickhouseClient) CountRedirectClicks(ctx context.Context, key string) (uint64, error) {
+	var total uint64
+	err := c.QueryRow(ctx, `SELECT count() FROM potatbotat.redirect_clicks WHERE key = ?`, key).Scan(&total)
+
+	return total, err
+}
+
+// GetRedirectStats aggregates the clicks of a redirect, bucketing them hourly since hourlySince
+// and daily since dailySince.
+func (c *ClickhouseClient) GetRedirectStats(
+	ctx context.Context,
+	key string,
+	hourlySince time.Time,
+	dailySince time.Time,
+) (*common.RedirectStats, error) {
+	stats := common.RedirectStats{Key: key}
+
+	err := c.QueryRow(
+		ctx,
+		`SELECT count(), countIf(cache_hit) FROM potatbotat.redirect_clicks WHERE key = ?`,
+		key,
+	).Scan(&stats.Total, &stats.CacheHits)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Hourly, err = c.timeBuckets(ctx, "toStartOfHour", key, hourlySince); err != nil {
+		return nil, err
+	}
+	if stats.Daily, err = c.timeBuckets(ctx, "toStartOfDay", key, dailySince); err != nil {
+		return nil, err
+	}
+	if stats.Referrers, err = c.topValues(ctx, "referrer_host", key); err != nil {
+		return nil, err
+	}
+	if stats.Countries, err = c.topValues(ctx, "country", key); err != nil {
+		return nil, err
+	}
+	if stats.UserAgents, err = c.topValues(ctx, "user_agent_class", key); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// bucket is one of a fixed set of ClickHouse functions and is never user input.
+func (c *ClickhouseClient) timeBuckets(
+	ctx context.Context,
+	bucket string,
+	key string,
+	since time.Time,
+) ([]common.StatBucket, error) {
+	query := `
+		SELECT ` + bucket + `(timestamp) AS bucket, count()
+		FROM potatbotat.redirect_clicks
+		WHERE key = ? AND timestamp >= ?
+		GROUP BY bucket
+		ORDER BY bucket;
+	`
+
+	rows, err := c.Query(ctx, query, key, since)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	buckets := make([]common.StatBucket, 0)
+	for rows.Next() {
+		var bucketTime time.Time
+		var count uint64
+		if err = rows.Scan(&bucketTime, &count); err != nil {
+			return nil, err
+		}
+
+		buckets = append(buckets, common.StatBucket{Time: &bucketTime, Count: count})
+	}
+
+	return buckets, rows.Err()
+}
+
+// column is one of a fixed set of table columns and is never user input.
+func (c *ClickhouseClient) topValues(ctx context.Context, column, key string) ([]common.StatBucket, error) {
+	query := `
+		SELECT ` + column + ` AS value, count() AS total
+		FROM potatbotat.redirect_clicks
+		WHERE key = ?
+		GROUP BY value
+		ORDER BY total DESC
+		LIMIT ?;
+	`
+
+	rows, err := c.Query(ctx, query, key, statsTopLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	values := make([]common.StatBucket, 0)
+	for rows.Next() {
+		var bucket common.StatBucket
+		if err = rows.Scan(&bucket.Value, &bucket.Count); err != nil {
+			return nil, err
+		}
+
+		values = append(values, bucket)
+	}
+
+	return values, rows.Err()
+}
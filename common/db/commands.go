@@ -0,0 +1,174 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrOrderMismatch is returned when a reorder does not list every command of the channel exactly once.
+var ErrOrderMismatch = errors.New("order must contain every command exactly once")
+
+// CreateChannelCommand inserts a new custom channel command, filling in its ID and timestamps.
+func (db *PostgresClient) CreateChannelCommand(ctx context.Context, command *common.ChannelCommand) error {
+	query := `
+		INSERT INTO custom_channel_commands (
+			user_id,
+			channel_id,
+			name,
+			user_trigger_ids,
+			user_ignore_ids,
+			trigger,
+			response,
+			run_command,
+			active,
+			active_online,
+			active_offline,
+			reply,
+			whisper,
+			announce,
+			cooldown,
+			delay,
+			platform,
+			help,
+			position,
+			created,
+			modified
+		)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+			$11, $12, $13, $14, $15, $16, $17, $18,
+			(SELECT COALESCE(MAX(position) + 1, 0) FROM custom_channel_commands WHERE channel_id = $2),
+			NOW(),
+			NOW()
+		)
+		RETURNING command_id, position, created, modified;
+	`
+
+	return db.Pool.QueryRow(
+		ctx,
+		query,
+		command.UserID,
+		command.ChannelID,
+		command.Name,
+		command.UserTriggerIDs,
+		command.UserIgnoreIDs,
+		command.Trigger,
+		command.Response,
+		command.RunCommand,
+		command.Active,
+		command.ActiveOnline,
+		command.ActiveOffline,
+		command.Reply,
+		command.Whisper,
+		command.Announce,
+		command.Cooldown,
+		command.Delay,
+		command.Platform,
+		command.Help,
+	).Scan(
+		&command.CommandID,
+		&command.Position,
+		&command.Created,
+		&command.Modified,
+	)
+}
+
+// UpdateChannelCommand updates the editable fields of a custom channel command and bumps its modified time.
+func (db *PostgresClient) UpdateChannelCommand(ctx context.Context, command *common.ChannelCommand) error {
+	query := `
+		UPDATE custom_channel_commands
+		SET
+			name = $3,
+			user_trigger_ids = $4,
+			user_ignore_ids = $5,
+			trigger = $6,
+			response = $7,
+			run_command = $8,
+			active = $9,
+			active_online = $10,
+			active_offline = $11,
+			reply = $12,
+			whisper = $13,
+			announce = $14,
+			cooldown = $15,
+			delay = $16,
+			help = $17,
+			modified = NOW()
+		WHERE command_id = $1 AND channel_id = $2
+		RETURNING modified;
+	`
+
+	return db.Pool.QueryRow(
+		ctx,
+		query,
+		command.CommandID,
+		command.ChannelID,
+		command.Name,
+		command.UserTriggerIDs,
+		command.UserIgnoreIDs,
+		command.Trigger,
+		command.Response,
+		command.RunCommand,
+		command.Active,
+		command.ActiveOnline,
+		command.ActiveOffline,
+		command.Reply,
+		command.Whisper,
+		command.Announce,
+		command.Cooldown,
+		command.Delay,
+		command.Help,
+	).Scan(&command.Modified)
+}
+
+// DeleteChannelCommand deletes a custom channel command, returning false if it did not exist.
+func (db *PostgresClient) DeleteChannelCommand(ctx context.Context, channelID string, commandID int) (bool, error) {
+	query := `
+		DELETE FROM custom_channel_commands
+		WHERE command_id = $1 AND channel_id = $2;
+	`
+
+	tag, err := db.Pool.Exec(ctx, query, commandID, channelID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReorderChannelCommands sets the position of each command in a channel to its index in order.
+func (db *PostgresClient) ReorderChannelCommands(ctx context.Context, channelID string, order []int) error {
+	return pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		query := `
+			UPDATE custom_channel_commands AS c
+			SET position = o.position - 1, modified = NOW()
+			FROM unnest($2::INT[]) WITH ORDINALITY AS o(command_id, position)
+			WHERE c.command_id = o.command_id AND c.channel_id = $1;
+		`
+
+		tag, err := tx.Exec(ctx, query, channelID, order)
+		if err != nil {
+			return err
+		}
+
+		var total int64
+		err = tx.QueryRow(
+			ctx,
+			`SELECT COUNT(*) FROM custom_channel_commands WHERE channel_id = $1`,
+			channelID,
+		).Scan(&total)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() != total || int64(len(order)) != total {
+			return ErrOrderMismatch
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,207 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxHasteHistory limits how many forks GetHasteHistory follows back.
+const maxHasteHistory = 100
+
+// HasteCacheKey returns the Redis key a hastebin document is cached under.
+func HasteCacheKey(key string) string {
+	return "haste:" + encode(key)
+}
+
+// HasteHTMLCacheKey returns the Redis hash the highlighted pages of a hastebin document are cached
+// under, with one field per language.
+func HasteHTMLCacheKey(key string) string {
+	return HasteCacheKey(key) + ":html"
+}
+
+// GetHaste retrieves a hastebin text document from the database by its key. Burn after read
+// documents are deleted in the same statement, so only a single reader ever receives them.
+func (db *PostgresClient) GetHaste(ctx context.Context, key string) (*common.HasteDocument, error) {
+	burnQuery := `
+		DELETE FROM haste
+		WHERE key = $1
+		AND burn_after_read
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING
+			convert_from(zstd_decompress(content::bytea), 'utf-8') AS text,
+			timestamp,
+			expires_at,
+			COALESCE(language, ''),
+			COALESCE(parent_key, '');
+	`
+
+	readQuery := `
+		UPDATE haste
+		SET access_count = access_count + 1
+		WHERE key = $1
+		AND NOT burn_after_read
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING
+			convert_from(zstd_decompress(content::bytea), 'utf-8') AS text,
+			timestamp,
+			expires_at,
+			COALESCE(language, ''),
+			COALESCE(parent_key, '');
+	`
+
+	document := common.HasteDocument{Key: key, BurnAfterRead: true}
+
+	err := db.Pool.QueryRow(ctx, burnQuery, encode(key)).Scan(
+		&document.Content,
+		&document.CreatedAt,
+		&document.ExpiresAt,
+		&document.Language,
+		&document.Parent,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		document.BurnAfterRead = false
+		err = db.Pool.QueryRow(ctx, readQuery, encode(key)).Scan(
+			&document.Content,
+			&document.CreatedAt,
+			&document.ExpiresAt,
+			&document.Language,
+			&document.Parent,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// NewHaste inserts a new compressed hastebin text document into the database,
+// reporting false if the key is already taken.
+func (db *PostgresClient) NewHaste(
+	ctx context.Context,
+	document *common.HasteDocument,
+	text []byte,
+	source string,
+	deleteTokenHash string,
+) (bool, error) {
+	query := `
+		INSERT INTO haste (key, content, source, expires_at, burn_after_read, delete_token, language, parent_key)
+		VALUES ($1, zstd_compress($2, null, 8), $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+		ON CONFLICT (key) DO NOTHING
+		RETURNING timestamp;
+	`
+
+	err := db.Pool.QueryRow(
+		ctx,
+		query,
+		encode(document.Key),
+		text,
+		source,
+		document.ExpiresAt,
+		document.BurnAfterRead,
+		deleteTokenHash,
+		document.Language,
+		document.Parent,
+	).Scan(&document.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// GetHasteInfo retrieves the details of an unexpired hastebin document without its content,
+// so burn after read documents can be inspected without being deleted.
+func (db *PostgresClient) GetHasteInfo(ctx context.Context, key string) (*common.HasteDocument, error) {
+	query := `
+		SELECT timestamp, expires_at, COALESCE(language, ''), COALESCE(parent_key, ''), burn_after_read
+		FROM haste
+		WHERE key = $1
+		AND (expires_at IS NULL OR expires_at > NOW());
+	`
+
+	document := common.HasteDocument{Key: key}
+
+	err := db.Pool.QueryRow(ctx, query, encode(key)).Scan(
+		&document.CreatedAt,
+		&document.ExpiresAt,
+		&document.Language,
+		&document.Parent,
+		&document.BurnAfterRead,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &document, nil
+}
+
+// GetHasteHistory returns the chain of documents a hastebin document was forked from, oldest first
+// and ending with the document itself. The chain stops at the first parent that no longer exists.
+func (db *PostgresClient) GetHasteHistory(ctx context.Context, key string) ([]common.HasteRevision, error) {
+	query := `
+		WITH RECURSIVE history AS (
+			SELECT $1::TEXT AS raw_key, parent_key, timestamp, expires_at, language, 0 AS depth
+			FROM haste
+			WHERE key = $2
+			AND (expires_at IS NULL OR expires_at > NOW())
+			UNION ALL
+			SELECT history.parent_key::TEXT, h.parent_key, h.timestamp, h.expires_at, h.language, history.depth + 1
+			FROM history
+			JOIN haste h ON h.key = md5(history.parent_key)
+			WHERE (h.expires_at IS NULL OR h.expires_at > NOW())
+			AND history.depth < $3
+		)
+		SELECT timestamp, expires_at, raw_key, COALESCE(language, ''), COALESCE(parent_key, '')
+		FROM history
+		ORDER BY depth DESC;
+	`
+
+	rows, err := db.Pool.Query(ctx, query, key, encode(key), maxHasteHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[common.HasteRevision])
+}
+
+// GetHasteDeleteToken returns the hashed deletion token of a hastebin document,
+// which is empty for documents created before deletion tokens existed.
+func (db *PostgresClient) GetHasteDeleteToken(ctx context.Context, key string) (string, error) {
+	query := `SELECT COALESCE(delete_token, '') FROM haste WHERE key = $1`
+
+	var token string
+	err := db.Pool.QueryRow(ctx, query, encode(key)).Scan(&token)
+
+	return token, err
+}
+
+// DeleteHaste deletes a hastebin document, returning false if it did not exist.
+func (db *PostgresClient) DeleteHaste(ctx context.Context, key string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM haste WHERE key = $1`, encode(key))
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteExpiredHastes deletes every expired hastebin document, returning their cache keys.
+func (db *PostgresClient) DeleteExpiredHastes(ctx context.Context) ([]string, error) {
+	query := `
+		DELETE FROM haste
+		WHERE expires_at IS NOT NULL AND expires_at < NOW()
+		RETURNING 'haste:' || key;
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -0,0 +1,211 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	leaderboardPrefix     = "leaderboard:potatoes:"
+	leaderboardEntriesKey = leaderboardPrefix + "entries"
+	leaderboardBatchSize  = 1000
+)
+
+func leaderboardKey(sort common.LeaderboardSort) string {
+	return leaderboardPrefix + string(sort)
+}
+
+// GetPotatoLeaderboardEntries retrieves the leaderboard statistics of every potato player.
+// This is a full table scan and is only meant to be run from the leaderboard refresh loop.
+func (db *PostgresClient) GetPotatoLeaderboardEntries(ctx context.Context) ([]common.LeaderboardEntry, error) {
+	query := `
+		SELECT
+			u.user_id,
+			u.username,
+			u.display,
+			p.potato_count,
+			p.potato_prestige,
+			a.theft_count,
+			a.trample_count,
+			a.gamble_wins_total,
+			a.gamble_losses_total,
+			a.duel_wins_amount,
+			a.duel_losses_amount
+		FROM potatoes p
+		INNER JOIN users u ON u.user_id = p.user_id
+		INNER JOIN potato_analytics a ON a.user_id = p.user_id;
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := make([]common.LeaderboardEntry, 0)
+	for rows.Next() {
+		var entry common.LeaderboardEntry
+		err = rows.Scan(
+			&entry.ID,
+			&entry.Username,
+			&entry.Display,
+			&entry.PotatoCount,
+			&entry.PotatoPrestige,
+			&entry.TheftCount,
+			&entry.TrampleCount,
+			&entry.GambleWinsTotal,
+			&entry.GambleLossesTotal,
+			&entry.DuelWinsAmount,
+			&entry.DuelLossesAmount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// ReplaceLeaderboards rebuilds every leaderboard sorted set from the given entries,
+// swapping the new sets in atomically so readers never see a partial leaderboard.
+func (r *RedisClient) ReplaceLeaderboards(ctx context.Context, entries []common.LeaderboardEntry) error {
+	sorts := common.LeaderboardSorts()
+	suffix := ":building"
+
+	_, err := r.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, leaderboardEntriesKey+suffix)
+		for _, sort := range sorts {
+			pipe.Del(ctx, leaderboardKey(sort)+suffix)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(entries); start += leaderboardBatchSize {
+		batch := entries[start:min(start+leaderboardBatchSize, len(entries))]
+
+		_, err = r.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			details := make(map[string]interface{}, len(batch))
+			members := make(map[common.LeaderboardSort][]redis.Z, len(sorts))
+
+			for _, entry := range batch {
+				member := strconv.Itoa(entry.ID)
+
+				data, err := json.Marshal(entry)
+				if err != nil {
+					return err
+				}
+				details[member] = data
+
+				for _, sort := range sorts {
+					members[sort] = append(members[sort], redis.Z{
+						Score:  float64(entry.ScoreBy(sort)),
+						Member: member,
+					})
+				}
+			}
+
+			pipe.HSet(ctx, leaderboardEntriesKey+suffix, details)
+			for _, sort := range sorts {
+				pipe.ZAdd(ctx, leaderboardKey(sort)+suffix, members[sort]...)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	_, err = r.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Rename(ctx, leaderboardEntriesKey+suffix, leaderboardEntriesKey)
+		for _, sort := range sorts {
+			pipe.Rename(ctx, leaderboardKey(sort)+suffix, leaderboardKey(sort))
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// GetLeaderboardRange retrieves leaderboard entries ranked start through stop (zero based, inclusive),
+// along with the number of ranked users.
+func (r *RedisClient) GetLeaderboardRange(
+	ctx context.Context,
+	sort common.LeaderboardSort,
+	start int64,
+	stop int64,
+) ([]common.LeaderboardEntry, int64, error) {
+	var ranked *redis.ZSliceCmd
+	var total *redis.IntCmd
+
+	_, err := r.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		ranked = pipe.ZRevRangeWithScores(ctx, leaderboardKey(sort), start, stop)
+		total = pipe.ZCard(ctx, leaderboardKey(sort))
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	members := ranked.Val()
+	entries := make([]common.LeaderboardEntry, 0, len(members))
+	if len(members) == 0 {
+		return entries, total.Val(), nil
+	}
+
+	ids := make([]string, len(members))
+	for i, member := range members {
+		ids[i], _ = member.Member.(string)
+	}
+
+	details, err := r.HMGet(ctx, leaderboardEntriesKey, ids...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i, detail := range details {
+		raw, ok := detail.(string)
+		if !ok {
+			continue
+		}
+
+		var entry common.LeaderboardEntry
+		if err = json.Unmarshal([]byte(raw), &entry); err != nil {
+			return nil, 0, err
+		}
+
+		entry.Rank = start + int64(i) + 1
+		entry.Score = int64(members[i].Score)
+		entries = append(entries, entry)
+	}
+
+	return entries, total.Val(), nil
+}
+
+// GetLeaderboardPosition returns the zero based position of a user on a leaderboard.
+// Returns ErrRedisNil if the user is not ranked.
+func (r *RedisClient) GetLeaderboardPosition(
+	ctx context.Context,
+	sort common.LeaderboardSort,
+	userID int,
+) (int64, error) {
+	return r.ZRevRank(ctx, leaderboardKey(sort), strconv.Itoa(userID)).Result()
+}
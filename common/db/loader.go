@@ -0,0 +1,293 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+func (k LoaderKey) platform() string {
+	if k.Platform == nil || *k.Platform == "" {
+		return string(common.TWITCH)
+	}
+
+	return *k.Platform
+}
+
+func (k LoaderKey) byID() (int, bool) {
+	if k.ID == nil {
+		return 0, false
+	}
+
+	return *k.ID, true
+}
+
+func (k LoaderKey) byName() (string, bool) {
+	if k.Username == nil {
+		return "", false
+	}
+
+	return *k.Username, true
+}
+
+func splitLoaderKeys(keys []LoaderKey) ([]int, []string) {
+	ids := make([]int, 0, len(keys))
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if id, ok := key.byID(); ok {
+			ids = append(ids, id)
+		} else if name, ok := key.byName(); ok {
+			names = append(names, name)
+		}
+	}
+
+	return ids, names
+}
+
+// LoadUsers resolves users by internal ID or username in a single query.
+// The result is in the same order as keys, with nil for users that do not exist.
+func (db *PostgresClient) LoadUsers(ctx context.Context, keys []LoaderKey) ([]*common.User, error) {
+	query := `
+		SELECT
+			users.user_id,
+			users.username,
+			users.display,
+			users.first_seen,
+			users.level,
+			users.settings,
+			json_agg(uc) AS connections
+		FROM users
+		LEFT JOIN user_connections uc ON users.user_id = uc.user_id
+		WHERE users.user_id = ANY($1::INT[]) OR users.username = ANY($2::TEXT[])
+		GROUP BY users.user_id;
+	`
+
+	ids, names := splitLoaderKeys(keys)
+
+	rows, err := db.Pool.Query(ctx, query, ids, names)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	byID := make(map[int]*common.User, len(keys))
+	byName := make(map[string]*common.User, len(keys))
+	for rows.Next() {
+		var user common.User
+		err = rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Display,
+			&user.FirstSeen,
+			&user.Level,
+			&user.Settings,
+			&user.Connections,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		byID[user.ID] = &user
+		byName[user.Username] = &user
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	users := make([]*common.User, len(keys))
+	for i, key := range keys {
+		if id, ok := key.byID(); ok {
+			users[i] = byID[id]
+		} else if name, ok := key.byName(); ok {
+			users[i] = byName[name]
+		}
+	}
+
+	return users, nil
+}
+
+// LoadChannels resolves channels by channel ID (UserID) or username on the key's platform,
+// defaulting to Twitch, along with their commands and blocks in three queries total.
+// The result is in the same order as keys, with nil for channels that do not exist.
+func (db *PostgresClient) LoadChannels(ctx context.Context, keys []LoaderKey) ([]*common.Channel, error) {
+	query := `
+		SELECT
+			c.channel_id,
+			c.username,
+			c.joined_at,
+			c.added_by,
+			c.platform,
+			c.settings,
+			c.editors,
+			c.ambassadors,
+			c.meta,
+			c.state
+		FROM channels c
+		INNER JOIN unnest($1::TEXT[], $2::TEXT[], $3::TEXT[]) AS k(channel_id, username, platform)
+			ON c.platform::TEXT = k.platform
+			AND (c.channel_id = k.channel_id OR c.username = k.username);
+	`
+
+	channelIDs := make([]string, len(keys))
+	usernames := make([]string, len(keys))
+	platforms := make([]string, len(keys))
+	for i, key := range keys {
+		if key.UserID != nil {
+			channelIDs[i] = *key.UserID
+		} else if name, ok := key.byName(); ok {
+			usernames[i] = name
+		}
+		platforms[i] = key.platform()
+	}
+
+	rows, err := db.Pool.Query(ctx, query, channelIDs, usernames, platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	found := make(map[string]*common.Channel, len(keys))
+	for rows.Next() {
+		var channel common.Channel
+		err = rows.Scan(
+			&channel.ChannelID,
+			&channel.Username,
+			&channel.JoinedAt,
+			&channel.AddedBy,
+			&channel.Platform,
+			&channel.Settings,
+			&channel.Editors,
+			&channel.Ambassadors,
+			&channel.Meta,
+			&channel.State,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		found[string(channel.Platform)+":"+channel.ChannelID] = &channel
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(found))
+	for _, channel := range found {
+		ids = append(ids, channel.ChannelID)
+	}
+
+	commands, err := db.getCommandsByChannels(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := db.getBlocksByChannels(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*common.Channel, len(found))
+	for _, channel := range found {
+		channelCommands := commands[channel.ChannelID]
+		if channelCommands == nil {
+			channelCommands = make([]common.ChannelCommand, 0)
+		}
+		channel.Commands = &channelCommands
+		channel.Blocks = filterBlocks(blocks[channel.ChannelID])
+
+		byName[string(channel.Platform)+":"+channel.Username] = channel
+	}
+
+	channels := make([]*common.Channel, len(keys))
+	for i, key := range keys {
+		if key.UserID != nil {
+			channels[i] = found[key.platform()+":"+*key.UserID]
+		} else if name, ok := key.byName(); ok {
+			channels[i] = byName[key.platform()+":"+name]
+		}
+	}
+
+	return channels, nil
+}
+
+// LoadPotatoData resolves potato data by internal user ID or username in a single query.
+// The result is in the same order as keys, with nil for users that have never played.
+func (db *PostgresClient) LoadPotatoData(ctx context.Context, keys []LoaderKey) ([]*common.PotatoData, error) {
+	query := `
+		SELECT u.username, ` + potatoDataColumns + `
+		FROM users u
+		INNER JOIN potatoes p ON p.user_id = u.user_id
+		INNER JOIN potato_analytics a ON u.user_id = a.user_id
+		INNER JOIN potato_settings s ON u.user_id = s.user_id
+		WHERE u.user_id = ANY($1::INT[]) OR u.username = ANY($2::TEXT[]);
+	`
+
+	ids, names := splitLoaderKeys(keys)
+
+	rows, err := db.Pool.Query(ctx, query, ids, names)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	byID := make(map[int]*common.PotatoData, len(keys))
+	byName := make(map[string]*common.PotatoData, len(keys))
+	for rows.Next() {
+		var username string
+		var data common.PotatoData
+		if err = rows.Scan(append([]any{&username}, potatoDataFields(&data)...)...); err != nil {
+			return nil, err
+		}
+
+		byID[data.ID] = &data
+		byName[username] = &data
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	potatoes := make([]*common.PotatoData, len(keys))
+	for i, key := range keys {
+		if id, ok := key.byID(); ok {
+			potatoes[i] = byID[id]
+		} else if name, ok := key.byName(); ok {
+			potatoes[i] = byName[name]
+		}
+	}
+
+	return potatoes, nil
+}
+
+// MGetInts fetches several integer keys in one round trip, returning 0 for missing or non-integer values.
+func (r *RedisClient) MGetInts(ctx context.Context, keys ...string) ([]int, error) {
+	values := make([]int, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	results, err := r.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		raw, ok := result.(string)
+		if !ok {
+			continue
+		}
+
+		if value, err := strconv.Atoi(raw); err == nil {
+			values[i] = value
+		}
+	}
+
+	return values, nil
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/Potat-Industries/potat-api/common"
 	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
 	"github.com/Potat-Industries/potat-api/common/utils"
 	"github.com/robfig/cron/v3"
 )
@@ -99,8 +100,70 @@ func StartLoops(
 	cronManager.Start()
 
 	go decrementDuels(ctx, redis)
-	go deleteOldUploads(ctx, postgres)
+	go deleteOldUploads(ctx, config, postgres)
 	go updateAggregateTable(ctx, postgres)
+	go refreshLeaderboards(ctx, postgres, redis)
+	go deleteGoneRedirects(ctx, postgres, redis)
+	go deleteExpiredHastes(ctx, postgres, redis)
+}
+
+func deleteGoneRedirects(ctx context.Context, postgres *PostgresClient, redis *RedisClient) {
+	for {
+		time.Sleep(time.Hour)
+
+		keys, err := postgres.DeleteGoneRedirects(ctx)
+		if err != nil {
+			logger.Error.Println("Error deleting expired redirects ", err)
+
+			continue
+		}
+
+		if len(keys) > 0 {
+			if err = redis.Del(ctx, keys...).Err(); err != nil {
+				logger.Error.Println("Error uncaching expired redirects ", err)
+			}
+		}
+
+		logger.Debug.Printf("Deleted %d expired redirects", len(keys))
+	}
+}
+
+func deleteExpiredHastes(ctx context.Context, postgres *PostgresClient, redis *RedisClient) {
+	for {
+		time.Sleep(10 * time.Minute)
+
+		keys, err := postgres.DeleteExpiredHastes(ctx)
+		if err != nil {
+			logger.Error.Println("Error deleting expired documents ", err)
+
+			continue
+		}
+
+		if len(keys) > 0 {
+			if err = redis.Del(ctx, keys...).Err(); err != nil {
+				logger.Error.Println("Error uncaching expired documents ", err)
+			}
+		}
+
+		logger.Debug.Printf("Deleted %d expired documents", len(keys))
+	}
+}
+
+func refreshLeaderboards(ctx context.Context, postgres *PostgresClient, redis *RedisClient) {
+	for {
+		start := time.Now()
+
+		entries, err := postgres.GetPotatoLeaderboardEntries(ctx)
+		if err != nil {
+			logger.Error.Println("Failed fetching leaderboard entries", err)
+		} else if err = redis.ReplaceLeaderboards(ctx, entries); err != nil {
+			logger.Error.Println("Failed refreshing leaderboards", err)
+		} else {
+			logger.Debug.Printf("Refreshed leaderboards with %d users in %s", len(entries), time.Since(start))
+		}
+
+		time.Sleep(5 * time.Minute)
+	}
 }
 
 func decrementDuels(ctx context.Context, redis *RedisClient) {
@@ -141,23 +204,33 @@ func decrementDuels(ctx context.Context, redis *RedisClient) {
 	}
 }
 
-func deleteOldUploads(ctx context.Context, postgres *PostgresClient) {
+func deleteOldUploads(ctx context.Context, config common.Config, postgres *PostgresClient) {
+	store, err := storage.New(config.Storage)
+	if err != nil {
+		logger.Error.Println("Failed initializing upload storage ", err)
+
+		return
+	}
+
 	for {
 		time.Sleep(24 * time.Hour)
 		logger.Info.Println("Deleting old uploads")
 
-		query := `
-			DELETE FROM file_store
-			WHERE (created_at < NOW() - INTERVAL '30 days' AND expires_at IS NULL)
-			OR (expires_at IS NOT NULL AND expires_at < NOW());
-		`
-
-		_, err := postgres.Exec(ctx, query)
+		// Only blobs no other upload references anymore are returned.
+		blobKeys, err := postgres.DeleteOldUploads(ctx)
 		if err != nil {
 			logger.Error.Println("Error deleting old uploads ", err)
+
+			continue
+		}
+
+		for _, blobKey := range blobKeys {
+			if err = storage.DeleteBlob(ctx, store, blobKey); err != nil {
+				logger.Error.Printf("Error deleting blob %s from storage: %v", blobKey, err)
+			}
 		}
 
-		logger.Debug.Println("Deleted old uploads")
+		logger.Debug.Printf("Deleted old uploads and %d unused blobs", len(blobKeys))
 	}
 }
 
@@ -342,9 +415,9 @@ func updateUserOwnedBadgeView(ctx context.Context, clickhouse *ClickhouseClient)
 	}
 }
 
-func upsertOAuthToken(
+// UpsertOAuthToken inserts or replaces the stored Twitch OAuth token for a platform user.
+func (db *PostgresClient) UpsertOAuthToken(
 	ctx context.Context,
-	postgres *PostgresClient,
 	oauth *common.GenericOAUTHResponse,
 	con common.PlatformOauth,
 ) error {
@@ -369,7 +442,7 @@ func upsertOAuthToken(
 			added_at = EXCLUDED.added_at;
 	`
 
-	_, err := postgres.Exec(
+	_, err := db.Exec(
 		ctx,
 		query,
 		con.PlatformID,
@@ -400,7 +473,7 @@ func refreshOrDelete(
 		return false, err
 	}
 
-	err = upsertOAuthToken(ctx, postgres, refreshResult, con)
+	err = postgres.UpsertOAuthToken(ctx, refreshResult, con)
 	if err != nil {
 		logger.Error.Println(
 			"Error updating token for user_id", con.PlatformID, ":", err,
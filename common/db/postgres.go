@@ -104,6 +104,18 @@ func (db *PostgresClient) CheckTableExists(ctx context.Context, createTable stri
 	}
 }
 
+// Columns the shared queries rely on that are not part of the bot's original schema. These are
+// applied by every process, since command lookups are not limited to the API server.
+const alterCustomCommands = `
+	ALTER TABLE IF EXISTS custom_channel_commands
+	ADD COLUMN IF NOT EXISTS position INT DEFAULT 0 NOT NULL;
+`
+
+// MigrateSharedColumns adds the columns the queries in this package need to existing tables.
+func (db *PostgresClient) MigrateSharedColumns(ctx context.Context) {
+	db.CheckTableExists(ctx, alterCustomCommands)
+}
+
 // Ping checks the connection to the database.
 func (db *PostgresClient) Ping(ctx context.Context) error {
 	return db.Pool.Ping(ctx)
@@ -177,27 +189,89 @@ func (db *PostgresClient) GetUserByInternalID(ctx context.Context, id int) (*com
 	return &user, nil
 }
 
+// GetUserByConnection retrieves a user by the ID of one of their platform connections.
+func (db *PostgresClient) GetUserByConnection(
+	ctx context.Context,
+	platformID string,
+	platform common.Platforms,
+) (*common.User, error) {
+	query := `
+		SELECT
+			u.user_id,
+			username,
+			display,
+			first_seen,
+			level,
+			settings,
+			json_agg(uc) as connections
+		FROM users u
+		JOIN user_connections uc ON u.user_id = uc.user_id
+		WHERE u.user_id = (
+			SELECT user_id
+			FROM user_connections
+			WHERE platform_id = $1 AND platform = $2
+			LIMIT 1
+		)
+		GROUP BY u.user_id;
+	`
+
+	var user common.User
+	err := db.Pool.QueryRow(ctx, query, platformID, platform).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Display,
+		&user.FirstSeen,
+		&user.Level,
+		&user.Settings,
+		&user.Connections,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 // GetChannelBlocks retrieves all blocks for a given channel from the database.
 func (db *PostgresClient) GetChannelBlocks(ctx context.Context, channelID string) *[]common.Block {
+	blocks, err := db.getBlocksByChannels(ctx, []string{channelID})
+	if err != nil {
+		logger.Warn.Println("Error fetching channel blocks: ", err)
+
+		return nil
+	}
+
+	channelBlocks := blocks[channelID]
+	if channelBlocks == nil {
+		channelBlocks = make([]common.Block, 0)
+	}
+
+	return &channelBlocks
+}
+
+func (db *PostgresClient) getBlocksByChannels(
+	ctx context.Context,
+	channelIDs []string,
+) (map[string][]common.Block, error) {
 	query := `
 		SELECT
-		  user_id
-			block_id,
+			user_id,
+			COALESCE(block_id, 0),
 			channel_id,
 			block_type,
-			block_data
+			COALESCE(block_data, '')
 		FROM blocks
-		WHERE channel_id = $1
+		WHERE channel_id = ANY($1::TEXT[])
 	`
 
-	rows, err := db.Pool.Query(ctx, query, channelID)
+	rows, err := db.Pool.Query(ctx, query, channelIDs)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	defer rows.Close()
 
-	var blocks []common.Block
+	blocks := make(map[string][]common.Block, len(channelIDs))
 	for rows.Next() {
 		var block common.Block
 		err := rows.Scan(
@@ -208,17 +282,61 @@ func (db *PostgresClient) GetChannelBlocks(ctx context.Context, channelID string
 			&block.CommandName,
 		)
 		if err != nil {
-			return nil
+			return nil, err
 		}
 
-		blocks = append(blocks, block)
+		blocks[block.ChannelID] = append(blocks[block.ChannelID], block)
+	}
+
+	return blocks, rows.Err()
+}
+
+func filterBlocks(blocks []common.Block) common.FilteredBlocks {
+	if len(blocks) == 0 {
+		return common.FilteredBlocks{}
+	}
+
+	filtered := common.FilteredBlocks{
+		Users:    &[]common.Block{},
+		Commands: &[]common.Block{},
+		Global:   &[]common.Block{},
+	}
+
+	for _, block := range blocks {
+		switch block.BlockType {
+		case common.UserBlock:
+			*filtered.Users = append(*filtered.Users, block)
+		case common.CommandBlock:
+			*filtered.Commands = append(*filtered.Commands, block)
+		case common.GlobalBlock:
+			*filtered.Global = append(*filtered.Global, block)
+		}
 	}
 
-	return &blocks
+	return filtered
 }
 
 // GetChannelCommands retrieves all custom channel commands for a given channel from the database.
 func (db *PostgresClient) GetChannelCommands(ctx context.Context, channelID string) *[]common.ChannelCommand {
+	commands, err := db.getCommandsByChannels(ctx, []string{channelID})
+	if err != nil {
+		logger.Warn.Println("Error fetching channel commands: ", err)
+
+		return nil
+	}
+
+	channelCommands := commands[channelID]
+	if channelCommands == nil {
+		channelCommands = make([]common.ChannelCommand, 0)
+	}
+
+	return &channelCommands
+}
+
+func (db *PostgresClient) getCommandsByChannels(
+	ctx context.Context,
+	channelIDs []string,
+) (map[string][]common.ChannelCommand, error) {
 	query := `
 		SELECT
 			command_id,
@@ -242,19 +360,21 @@ func (db *PostgresClient) GetChannelCommands(ctx context.Context, channelID stri
 			created,
 			modified,
 			platform,
-			help
+			help,
+			position
 		FROM custom_channel_commands
-		WHERE channel_id = $1
+		WHERE channel_id = ANY($1::TEXT[])
+		ORDER BY position, command_id
 	`
 
-	rows, err := db.Pool.Query(ctx, query, channelID)
+	rows, err := db.Pool.Query(ctx, query, channelIDs)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	defer rows.Close()
 
-	var commands []common.ChannelCommand
+	commands := make(map[string][]common.ChannelCommand, len(channelIDs))
 	for rows.Next() {
 		var command common.ChannelCommand
 		err := rows.Scan(
@@ -280,15 +400,16 @@ func (db *PostgresClient) GetChannelCommands(ctx context.Context, channelID stri
 			&command.Modified,
 			&command.Platform,
 			&command.Help,
+			&command.Position,
 		)
 		if err != nil {
-			return nil
+			return nil, err
 		}
 
-		commands = append(commands, command)
+		commands[command.ChannelID] = append(commands[command.ChannelID], command)
 	}
 
-	return &commands
+	return commands, rows.Err()
 }
 
 // GetChannelByName retrieves a channel by its username and platform from the database.
@@ -364,19 +485,13 @@ func (db *PostgresClient) getChannelByType( //nolint:cyclop
 	var commands *[]common.ChannelCommand
 	go func() {
 		defer wg.Done()
-		cmds := db.GetChannelCommands(ctx, channel.ChannelID)
-		if cmds != nil {
-			commands = cmds
-		}
+		commands = db.GetChannelCommands(ctx, channel.ChannelID)
 	}()
 
-	var blocks []common.Block
+	var blocks *[]common.Block
 	go func() {
 		defer wg.Done()
-		bs := db.GetChannelBlocks(ctx, channel.ChannelID)
-		if bs != nil {
-			blocks = *bs
-		}
+		blocks = db.GetChannelBlocks(ctx, channel.ChannelID)
 	}()
 
 	wg.Wait()
@@ -387,19 +502,8 @@ func (db *PostgresClient) getChannelByType( //nolint:cyclop
 		channel.Commands = &[]common.ChannelCommand{}
 	}
 
-	if len(blocks) > 0 {
-		channel.Blocks = common.FilteredBlocks{
-			Users:    &[]common.Block{},
-			Commands: &[]common.Block{},
-		}
-
-		for _, block := range blocks {
-			if block.BlockType == common.UserBlock {
-				*channel.Blocks.Users = append(*channel.Blocks.Users, block)
-			} else if block.BlockType == common.CommandBlock {
-				*channel.Blocks.Commands = append(*channel.Blocks.Commands, block)
-			}
-		}
+	if blocks != nil {
+		channel.Blocks = filterBlocks(*blocks)
 	} else {
 		channel.Blocks = common.FilteredBlocks{}
 	}
@@ -407,53 +511,45 @@ func (db *PostgresClient) getChannelByType( //nolint:cyclop
 	return &channel, nil
 }
 
-// GetPotatoData retrieves potato data for a user from the database.
-func (db *PostgresClient) GetPotatoData(ctx context.Context, username string) (*common.PotatoData, error) {
-	query := `
-		SELECT
-			p.user_id,
-			p.potato_count,
-			p.potato_prestige,
-			p.potato_rank,
-			p.tax_multiplier,
-			p.first_seen,
-			p.stole_from,
-			p.stole_amount,
-			p.trampled_by,
-			a.average_response_time,
-			a.eat_count,
-			a.harvest_count,
-			a.stolen_count,
-			a.theft_count,
-			a.trampled_count,
-			a.trample_count,
-			a.cdr_count,
-			a.quiz_count,
-			a.quiz_complete_count,
-			a.guard_buy_count,
-			a.fertilizer_buy_count,
-			a.cdr_buy_count,
-			a.new_quiz_buy_count,
-			a.gamble_win_count,
-			a.gamble_loss_count,
-			a.gamble_wins_total,
-			a.gamble_losses_total,
-			a.duel_win_count,
-			a.duel_loss_count,
-			a.duel_wins_amount,
-			a.duel_losses_amount,
-			a.duel_caught_losses,
-			a.average_response_count,
-			s.not_verbose
-		FROM ( SELECT user_id FROM users WHERE username = $1 ) u
-		INNER JOIN potatoes p ON p.user_id = u.user_id
-		INNER JOIN potato_analytics a ON u.user_id = a.user_id
-		INNER JOIN potato_settings s ON u.user_id = s.user_id;
-	`
-
-	var data common.PotatoData
-
-	err := db.Pool.QueryRow(ctx, query, username).Scan(
+const potatoDataColumns = `
+	p.user_id,
+	p.potato_count,
+	p.potato_prestige,
+	p.potato_rank,
+	p.tax_multiplier,
+	p.first_seen,
+	p.stole_from,
+	p.stole_amount,
+	p.trampled_by,
+	a.average_response_time,
+	a.eat_count,
+	a.harvest_count,
+	a.stolen_count,
+	a.theft_count,
+	a.trampled_count,
+	a.trample_count,
+	a.cdr_count,
+	a.quiz_count,
+	a.quiz_complete_count,
+	a.guard_buy_count,
+	a.fertilizer_buy_count,
+	a.cdr_buy_count,
+	a.new_quiz_buy_count,
+	a.gamble_win_count,
+	a.gamble_loss_count,
+	a.gamble_wins_total,
+	a.gamble_losses_total,
+	a.duel_win_count,
+	a.duel_loss_count,
+	a.duel_wins_amount,
+	a.duel_losses_amount,
+	a.duel_caught_losses,
+	a.average_response_count,
+	s.not_verbose
+`
+
+func potatoDataFields(data *common.PotatoData) []any {
+	return []any{
 		&data.ID,
 		&data.PotatoCount,
 		&data.PotatoPrestige,
@@ -488,7 +584,22 @@ func (db *PostgresClient) GetPotatoData(ctx context.Context, username string) (*
 		&data.DuelCaughtLosses,
 		&data.AverageResponseCount,
 		&data.NotVerbose,
-	)
+	}
+}
+
+// GetPotatoData retrieves potato data for a user from the database.
+func (db *PostgresClient) GetPotatoData(ctx context.Context, username string) (*common.PotatoData, error) {
+	query := `
+		SELECT ` + potatoDataColumns + `
+		FROM ( SELECT user_id FROM users WHERE username = $1 ) u
+		INNER JOIN potatoes p ON p.user_id = u.user_id
+		INNER JOIN potato_analytics a ON u.user_id = a.user_id
+		INNER JOIN potato_settings s ON u.user_id = s.user_id;
+	`
+
+	var data common.PotatoData
+
+	err := db.Pool.QueryRow(ctx, query, username).Scan(potatoDataFields(&data)...)
 	if err != nil {
 		return nil, err
 	}
@@ -543,22 +654,19 @@ func (db *PostgresClient) BatchUserConections(
 	return &users
 }
 
-// GetRedirectByKey retrieves a URL redirect from the database by its key.
-func (db *PostgresClient) GetRedirectByKey(ctx context.Context, key string) (string, error) {
-	query := `SELECT url FROM url_redirects WHERE key = $1`
-
-	var url string
-	err := db.Pool.QueryRow(ctx, query, key).Scan(&url)
-	if err != nil {
-		return "", err
-	}
-
-	return url, nil
-}
-
-// GetKeyByRedirect retrieves the key associated with a given URL redirect from the database.
+// GetKeyByRedirect retrieves the key of a plain anonymous redirect to the given URL,
+// links with an alias, owner, limits or a forced preview are never shared.
 func (db *PostgresClient) GetKeyByRedirect(ctx context.Context, url string) (string, error) {
-	query := `SELECT key FROM url_redirects WHERE url = $1`
+	query := `
+		SELECT key FROM url_redirects
+		WHERE url = $1
+		AND owner_id IS NULL
+		AND expires_at IS NULL
+		AND max_clicks IS NULL
+		AND NOT custom
+		AND NOT preview
+		LIMIT 1
+	`
 
 	var key string
 	err := db.Pool.QueryRow(ctx, query, url).Scan(&key)
@@ -569,157 +677,9 @@ func (db *PostgresClient) GetKeyByRedirect(ctx context.Context, url string) (str
 	return key, nil
 }
 
-// RedirectExists checks if a URL redirect exists in the database by its key.
-func (db *PostgresClient) RedirectExists(ctx context.Context, key string) bool {
-	query := `SELECT EXISTS(SELECT 1 FROM url_redirects WHERE key = $1)`
-
-	var exists bool
-
-	err := db.Pool.QueryRow(ctx, query, key).Scan(&exists)
-	if err != nil {
-		return false
-	}
-
-	return exists
-}
-
-// NewRedirect inserts a new URL redirect into the database.
-func (db *PostgresClient) NewRedirect(ctx context.Context, key, url string) error {
-	query := `INSERT INTO url_redirects (key, url) VALUES ($1, $2)`
-
-	_, err := db.Pool.Exec(ctx, query, key, url)
-
-	return err
-}
-
-// GetHaste retrieves a hastebin text document from the database by its key.
-func (db *PostgresClient) GetHaste(ctx context.Context, key string) (string, error) {
-	query := `
-		UPDATE haste
-		SET access_count = access_count + 1
-		WHERE key = $1
-		RETURNING convert_from(zstd_decompress(content::bytea), 'utf-8') AS text;
-	`
-
-	var text string
-
-	err := db.Pool.QueryRow(ctx, query, encode(key)).Scan(&text)
-	if err != nil {
-		return "", err
-	}
-
-	return text, nil
-}
-
-// NewHaste inserts a new compressed hastebin text document into the database.
-func (db *PostgresClient) NewHaste(
-	ctx context.Context,
-	key string,
-	text []byte,
-	source string,
-) error {
-	query := `
-		INSERT INTO haste (key, content, source)
-		VALUES ($1, zstd_compress($2, null, 8), $3)
-		ON CONFLICT (key) DO NOTHING;
-	`
-
-	_, err := db.Pool.Exec(ctx, query, encode(key), text, source)
-
-	return err
-}
-
 func encode(data string) string {
 	hash := md5.New() //nolint:gosec
 	hash.Write([]byte(data))
 
 	return hex.EncodeToString(hash.Sum(nil))
 }
-
-// NewUpload inserts a new file into the database and returns the creation timestamp.
-func (db *PostgresClient) NewUpload(
-	ctx context.Context,
-	key string,
-	file []byte,
-	name string,
-	mimeType string,
-) (bool, *time.Time) {
-	query := `
-		INSERT INTO file_store (file, file_name, mime_type, key)
-		VALUES ($1, $2, $3, $4)
-		RETURNING created_at;
-	`
-	var createdAt time.Time
-	err := db.Pool.QueryRow(ctx, query, file, name, mimeType, key).Scan(&createdAt)
-	if err != nil {
-		logger.Error.Println("Error scanning upload", err)
-
-		return false, nil
-	}
-
-	return true, &createdAt
-}
-
-// GetFileByKey retrieves a file from the database by its key.
-func (db *PostgresClient) GetFileByKey(
-	ctx context.Context,
-	key string,
-) ([]byte, string, *string, *time.Time, error) {
-	query := `
-		SELECT file, mime_type, file_name, created_at
-		FROM file_store
-		WHERE key = $1
-	`
-
-	var content []byte
-	var mimeType string
-	var fileName *string
-	var createdAt time.Time
-
-	err := db.Pool.QueryRow(ctx, query, key).Scan(
-		&content,
-		&mimeType,
-		&fileName,
-		&createdAt,
-	)
-	if err != nil {
-		return nil, "", nil, nil, err
-	}
-
-	return content, mimeType, fileName, &createdAt, nil
-}
-
-// DeleteFileByKey deletes a file from the database by its key.
-func (db *PostgresClient) DeleteFileByKey(
-	ctx context.Context,
-	key string,
-) bool {
-	query := `
-		DELETE FROM file_store
-		WHERE key = $1
-	`
-
-	_, err := db.Pool.Exec(ctx, query, key)
-
-	return err == nil
-}
-
-// GetUploadCreatedAt retrieves the creation timestamp of an upload by its key.
-func (db *PostgresClient) GetUploadCreatedAt(
-	ctx context.Context,
-	key string,
-) (*time.Time, error) {
-	query := `
-		SELECT created_at
-		FROM file_store
-		WHERE key = $1
-	`
-
-	var createdAt time.Time
-	err := db.Pool.QueryRow(ctx, query, key).Scan(&createdAt)
-	if err != nil {
-		return nil, err
-	}
-
-	return &createdAt, nil
-}
@@ -0,0 +1,236 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/jackc/pgx/v5"
+)
+
+const redirectColumns = `key, url, owner_id, expires_at, max_clicks, clicks, custom, preview, created_at`
+
+func redirectFields(redirect *common.Redirect) []any {
+	return []any{
+		&redirect.Key,
+		&redirect.URL,
+		&redirect.OwnerID,
+		&redirect.ExpiresAt,
+		&redirect.MaxClicks,
+		&redirect.Clicks,
+		&redirect.Custom,
+		&redirect.Preview,
+		&redirect.CreatedAt,
+	}
+}
+
+// GetRedirect retrieves a redirect and its metadata by key.
+func (db *PostgresClient) GetRedirect(ctx context.Context, key string) (*common.Redirect, error) {
+	query := `SELECT ` + redirectColumns + ` FROM url_redirects WHERE key = $1`
+
+	var redirect common.Redirect
+	if err := db.Pool.QueryRow(ctx, query, key).Scan(redirectFields(&redirect)...); err != nil {
+		return nil, err
+	}
+
+	return &redirect, nil
+}
+
+// CreateRedirect inserts a redirect with its metadata, returning false if the key is already taken.
+func (db *PostgresClient) CreateRedirect(ctx context.Context, redirect *common.Redirect) (bool, error) {
+	query := `
+		INSERT INTO url_redirects (key, url, owner_id, expires_at, max_clicks, custom, preview)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (key) DO NOTHING
+		RETURNING created_at;
+	`
+
+	err := db.Pool.QueryRow(
+		ctx,
+		query,
+		redirect.Key,
+		redirect.URL,
+		redirect.OwnerID,
+		redirect.ExpiresAt,
+		redirect.MaxClicks,
+		redirect.Custom,
+		redirect.Preview,
+	).Scan(&redirect.CreatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// UseLimitedRedirect counts a click against a redirect with a click limit, returning the updated
+// redirect. Returns pgx.ErrNoRows if the redirect no longer accepts clicks.
+func (db *PostgresClient) UseLimitedRedirect(ctx context.Context, key string) (*common.Redirect, error) {
+	query := `
+		UPDATE url_redirects
+		SET clicks = clicks + 1
+		WHERE key = $1
+		AND (max_clicks IS NULL OR clicks < max_clicks)
+		AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING ` + redirectColumns
+
+	var redirect common.Redirect
+	if err := db.Pool.QueryRow(ctx, query, key).Scan(redirectFields(&redirect)...); err != nil {
+		return nil, err
+	}
+
+	return &redirect, nil
+}
+
+// ListRedirectsByOwner retrieves a page of a user's redirects, newest first, with the total count.
+func (db *PostgresClient) ListRedirectsByOwner(
+	ctx context.Context,
+	ownerID int,
+	limit int,
+	offset int,
+) ([]common.Redirect, int, error) {
+	query := `
+		SELECT ` + redirectColumns + `, COUNT(*) OVER () AS total
+		FROM url_redirects
+		WHERE owner_id = $1
+		ORDER BY created_at DESC, key
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := db.Pool.Query(ctx, query, ownerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	total := 0
+	redirects := make([]common.Redirect, 0)
+	for rows.Next() {
+		var redirect common.Redirect
+		if err = rows.Scan(append(redirectFields(&redirect), &total)...); err != nil {
+			return nil, 0, err
+		}
+
+		redirects = append(redirects, redirect)
+	}
+
+	return redirects, total, rows.Err()
+}
+
+// UpdateRedirect updates the target, limits and preview flag of a redirect.
+func (db *PostgresClient) UpdateRedirect(ctx context.Context, redirect *common.Redirect) error {
+	query := `
+		UPDATE url_redirects
+		SET url = $2, expires_at = $3, max_clicks = $4, preview = $5
+		WHERE key = $1;
+	`
+
+	_, err := db.Pool.Exec(
+		ctx,
+		query,
+		redirect.Key,
+		redirect.URL,
+		redirect.ExpiresAt,
+		redirect.MaxClicks,
+		redirect.Preview,
+	)
+
+	return err
+}
+
+// DeleteRedirect deletes a redirect, returning false if it did not exist.
+func (db *PostgresClient) DeleteRedirect(ctx context.Context, key string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM url_redirects WHERE key = $1`, key)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteGoneRedirects deletes every expired or used up redirect, returning their keys.
+func (db *PostgresClient) DeleteGoneRedirects(ctx context.Context) ([]string, error) {
+	query := `
+		DELETE FROM url_redirects
+		WHERE (expires_at IS NOT NULL AND expires_at < NOW())
+		OR (max_clicks IS NOT NULL AND clicks >= max_clicks)
+		RETURNING key;
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// IsDomainDenied reports whether the host, or any domain it is a subdomain of, is on the redirect denylist.
+func (db *PostgresClient) IsDomainDenied(ctx context.Context, host string) (bool, error) {
+	labels := strings.Split(strings.ToLower(host), ".")
+	candidates := make([]string, len(labels))
+	for i := range labels {
+		candidates[i] = strings.Join(labels[i:], ".")
+	}
+
+	query := `SELECT EXISTS(SELECT 1 FROM redirect_denylist WHERE domain = ANY($1::TEXT[]))`
+
+	var denied bool
+	err := db.Pool.QueryRow(ctx, query, candidates).Scan(&denied)
+
+	return denied, err
+}
+
+// ListDeniedDomains returns every domain on the redirect denylist.
+func (db *PostgresClient) ListDeniedDomains(ctx context.Context) ([]common.DeniedDomain, error) {
+	query := `
+		SELECT domain, reason, added_by, created_at
+		FROM redirect_denylist
+		ORDER BY domain;
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (common.DeniedDomain, error) {
+		var domain common.DeniedDomain
+		err := row.Scan(&domain.Domain, &domain.Reason, &domain.AddedBy, &domain.CreatedAt)
+
+		return domain, err
+	})
+}
+
+// AddDeniedDomain adds a domain to the redirect denylist, returning false if it was already listed.
+func (db *PostgresClient) AddDeniedDomain(ctx context.Context, domain *common.DeniedDomain) (bool, error) {
+	query := `
+		INSERT INTO redirect_denylist (domain, reason, added_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (domain) DO NOTHING
+		RETURNING created_at;
+	`
+
+	err := db.Pool.QueryRow(ctx, query, domain.Domain, domain.Reason, domain.AddedBy).Scan(&domain.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// RemoveDeniedDomain removes a domain from the redirect denylist, returning false if it was not listed.
+func (db *PostgresClient) RemoveDeniedDomain(ctx context.Context, domain string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM redirect_denylist WHERE domain = $1`, domain)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,127 @@
+// Package db provides database clients and functions to retrieve or update data.
+package db
+
+import (
+	"context"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// UpdateChannelSettings replaces the settings of a channel in the database.
+func (db *PostgresClient) UpdateChannelSettings(
+	ctx context.Context,
+	channelID string,
+	platform common.Platforms,
+	settings common.ChannelSettings,
+) error {
+	query := `
+		UPDATE channels
+		SET settings = $1
+		WHERE channel_id = $2 AND platform = $3;
+	`
+
+	tag, err := db.Pool.Exec(ctx, query, settings, channelID, platform)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrPostgresNoRows
+	}
+
+	return nil
+}
+
+// GetCommandSettings retrieves the settings of a single command in a channel from the database.
+func (db *PostgresClient) GetCommandSettings(
+	ctx context.Context,
+	channelID string,
+	command string,
+) (*common.CommandSettings, error) {
+	query := `
+		SELECT
+			channel_id,
+			command,
+			permission,
+			users_blacklisted,
+			users_whitelisted,
+			custom_cooldown,
+			channel_usage,
+			is_enabled,
+			offline_only,
+			silent_errors,
+			allow_bots
+		FROM command_settings
+		WHERE channel_id = $1 AND command = $2;
+	`
+
+	var settings common.CommandSettings
+	err := db.Pool.QueryRow(ctx, query, channelID, command).Scan(
+		&settings.ChannelID,
+		&settings.Command,
+		&settings.Permission,
+		&settings.UsersBlacklisted,
+		&settings.UsersWhitelisted,
+		&settings.CustomCooldown,
+		&settings.ChannelUsage,
+		&settings.IsEnabled,
+		&settings.OfflineOnly,
+		&settings.SilentErrors,
+		&settings.AllowBots,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// UpsertCommandSettings inserts or updates the settings of a command in a channel.
+// Usage counters are owned by the bot and are never overwritten.
+func (db *PostgresClient) UpsertCommandSettings(
+	ctx context.Context,
+	settings common.CommandSettings,
+) error {
+	query := `
+		INSERT INTO command_settings (
+			channel_id,
+			command,
+			permission,
+			users_blacklisted,
+			users_whitelisted,
+			custom_cooldown,
+			is_enabled,
+			offline_only,
+			silent_errors,
+			allow_bots
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (channel_id, command)
+		DO UPDATE SET
+			permission = EXCLUDED.permission,
+			users_blacklisted = EXCLUDED.users_blacklisted,
+			users_whitelisted = EXCLUDED.users_whitelisted,
+			custom_cooldown = EXCLUDED.custom_cooldown,
+			is_enabled = EXCLUDED.is_enabled,
+			offline_only = EXCLUDED.offline_only,
+			silent_errors = EXCLUDED.silent_errors,
+			allow_bots = EXCLUDED.allow_bots;
+	`
+
+	_, err := db.Pool.Exec(
+		ctx,
+		query,
+		settings.ChannelID,
+		settings.Command,
+		settings.Permission,
+		settings.UsersBlacklisted,
+		settings.UsersWhitelisted,
+		settings.CustomCooldown,
+		settings.IsEnabled,
+		settings.OfflineOnly,
+		settings.SilentErrors,
+		settings.AllowBots,
+	)
+
+	return err
+}
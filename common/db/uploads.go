@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// Uploads reference their contents through file_blobs, which stores each distinct file once and
+// counts how many uploads use it. Uploads from before deduplication have no blob_key, their
+// contents are stored under their own key, or in the file column until they are migrated.
+const uploadColumns = `
+	created_at,
+	expires_at,
+	file_name,
+	owner_id,
+	COALESCE(blob_key, key),
+	key,
+	mime_type,
+	COALESCE(sha256, ''),
+	COALESCE(size, length(file), 0),
+	COALESCE(width, 0),
+	COALESCE(height, 0),
+	file IS NOT NULL
+`
+
+// NewUpload inserts the metadata of a new file into the database and returns the creation timestamp,
+// or ErrPostgresNoRows if the key is already taken. The blob must already be claimed with ClaimUploadBlob.
+func (db *PostgresClient) NewUpload(ctx context.Context, upload *common.Upload) (*time.Time, error) {
+	query := `
+		INSERT INTO file_store (key, file_name, mime_type, size, sha256, blob_key, width, height, owner_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), NULLIF($8, 0), $9)
+		ON CONFLICT (key) DO NOTHING
+		RETURNING created_at;
+	`
+	var createdAt time.Time
+	err := db.Pool.QueryRow(
+		ctx,
+		query,
+		upload.Key,
+		upload.FileName,
+		upload.MimeType,
+		upload.Size,
+		upload.SHA256,
+		upload.BlobKey,
+		upload.Width,
+		upload.Height,
+		upload.OwnerID,
+	).Scan(&createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createdAt, nil
+}
+
+// ClaimUploadBlob adds a reference to the blob holding content with the given hash. If no upload
+// uses that content yet, blobKey becomes its blob, otherwise the key of the existing blob is returned
+// and the blob stored under blobKey is no longer needed.
+func (db *PostgresClient) ClaimUploadBlob(ctx context.Context, sha256, blobKey string, size int64) (string, error) {
+	query := `
+		INSERT INTO file_blobs (sha256, blob_key, size, refs)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (sha256) DO UPDATE SET refs = file_blobs.refs + 1
+		RETURNING blob_key;
+	`
+
+	var claimed string
+	err := db.Pool.QueryRow(ctx, query, sha256, blobKey, size).Scan(&claimed)
+
+	return claimed, err
+}
+
+// ReleaseUploadBlob drops a reference to a blob, returning its key once nothing references it
+// anymore and it should be deleted from storage.
+func (db *PostgresClient) ReleaseUploadBlob(ctx context.Context, sha256 string) (string, error) {
+	var blobKey string
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		var err error
+		blobKey, err = releaseBlob(ctx, tx, sha256)
+
+		return err
+	})
+
+	return blobKey, err
+}
+
+func releaseBlob(ctx context.Context, tx pgx.Tx, sha256 string) (string, error) {
+	query := `
+		UPDATE file_blobs SET refs = refs - 1
+		WHERE sha256 = $1
+		RETURNING blob_key, refs;
+	`
+
+	var blobKey string
+	var refs int
+	err := tx.QueryRow(ctx, query, sha256).Scan(&blobKey, &refs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil || refs > 0 {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM file_blobs WHERE sha256 = $1 AND refs <= 0`, sha256)
+	if err != nil {
+		return "", err
+	}
+
+	return blobKey, nil
+}
+
+// GetUpload retrieves the metadata of a file by its key.
+func (db *PostgresClient) GetUpload(ctx context.Context, key string) (*common.Upload, error) {
+	query := `SELECT ` + uploadColumns + ` FROM file_store WHERE key = $1`
+
+	rows, err := db.Pool.Query(ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByPos[common.Upload])
+}
+
+// FindUploadBySHA256 returns the newest upload of identical content by the same owner that won't
+// expire within a day, so re-uploading a file can reuse its key. Anonymous uploads are never
+// matched, since they would share their delete hash with anyone uploading the same content.
+func (db *PostgresClient) FindUploadBySHA256(
+	ctx context.Context,
+	sha256 string,
+	ownerID int,
+) (*common.Upload, error) {
+	query := `
+		SELECT ` + uploadColumns + `
+		FROM file_store
+		WHERE sha256 = $1
+		AND owner_id = $2
+		AND file IS NULL
+		AND COALESCE(expires_at, created_at + INTERVAL '30 days') > NOW() + INTERVAL '1 day'
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	rows, err := db.Pool.Query(ctx, query, sha256, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByPos[common.Upload])
+}
+
+// GetLegacyUploadFile retrieves the contents of a file still stored in the database.
+func (db *PostgresClient) GetLegacyUploadFile(ctx context.Context, key string) ([]byte, error) {
+	query := `SELECT file FROM file_store WHERE key = $1 AND file IS NOT NULL`
+
+	var file []byte
+	err := db.Pool.QueryRow(ctx, query, key).Scan(&file)
+
+	return file, err
+}
+
+// ListLegacyUploads returns the keys of up to limit files whose contents are still stored in the database.
+func (db *PostgresClient) ListLegacyUploads(ctx context.Context, limit int) ([]string, error) {
+	query := `SELECT key FROM file_store WHERE file IS NOT NULL ORDER BY created_at LIMIT $1`
+
+	rows, err := db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ClearLegacyUploadFile drops the contents of a file from the database once they are in blob storage.
+// The blob must already be claimed with ClaimUploadBlob.
+func (db *PostgresClient) ClearLegacyUploadFile(
+	ctx context.Context,
+	key string,
+	size int64,
+	sha256 string,
+	blobKey string,
+) error {
+	query := `UPDATE file_store SET file = NULL, size = $2, sha256 = $3, blob_key = $4 WHERE key = $1`
+
+	_, err := db.Pool.Exec(ctx, query, key, size, sha256, blobKey)
+
+	return err
+}
+
+// DeleteUpload deletes a file from the database by its key, returning the key of its blob if
+// nothing else references it anymore and it should be deleted from storage.
+func (db *PostgresClient) DeleteUpload(ctx context.Context, key string) (string, error) {
+	query := `
+		DELETE FROM file_store
+		WHERE key = $1
+		RETURNING blob_key, key, COALESCE(sha256, ''), file IS NULL;
+	`
+
+	var blobKey string
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, key)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[deletedUpload])
+		if err != nil {
+			return err
+		}
+
+		blobKey, err = deleted.release(ctx, tx)
+
+		return err
+	})
+
+	return blobKey, err
+}
+
+// DeleteOldUploads deletes expired files and files older than 30 days without an expiry,
+// returning the keys of blobs that are no longer referenced.
+func (db *PostgresClient) DeleteOldUploads(ctx context.Context) ([]string, error) {
+	query := `
+		DELETE FROM file_store
+		WHERE (created_at < NOW() - INTERVAL '30 days' AND expires_at IS NULL)
+		OR (expires_at IS NOT NULL AND expires_at < NOW())
+		RETURNING blob_key, key, COALESCE(sha256, ''), file IS NULL;
+	`
+
+	_, blobKeys, err := db.deleteUploads(ctx, query)
+
+	return blobKeys, err
+}
+
+// DeleteOwnedUploads deletes the given files of a user, returning the keys of the deleted files
+// and of blobs that are no longer referenced.
+func (db *PostgresClient) DeleteOwnedUploads(
+	ctx context.Context,
+	ownerID int,
+	keys []string,
+) ([]string, []string, error) {
+	query := `
+		DELETE FROM file_store
+		WHERE owner_id = $1 AND key = ANY($2)
+		RETURNING blob_key, key, COALESCE(sha256, ''), file IS NULL;
+	`
+
+	return db.deleteUploads(ctx, query, ownerID, keys)
+}
+
+// deleteUploads runs a query deleting files and releases their blobs in the same transaction.
+func (db *PostgresClient) deleteUploads(ctx context.Context, query string, args ...any) ([]string, []string, error) {
+	var keys, blobKeys []string
+	err := pgx.BeginFunc(ctx, db.Pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		deleted, err := pgx.CollectRows(rows, pgx.RowToStructByPos[deletedUpload])
+		if err != nil {
+			return err
+		}
+
+		for _, upload := range deleted {
+			keys = append(keys, upload.Key)
+
+			blobKey, err := upload.release(ctx, tx)
+			if err != nil {
+				return err
+			}
+			if blobKey != "" {
+				blobKeys = append(blobKeys, blobKey)
+			}
+		}
+
+		return nil
+	})
+
+	return keys, blobKeys, err
+}
+
+// ListUploadsByOwner retrieves a page of a user's files, newest first, with the total count.
+func (db *PostgresClient) ListUploadsByOwner(
+	ctx context.Context,
+	ownerID int,
+	limit int,
+	offset int,
+) ([]common.Upload, int, error) {
+	query := `
+		SELECT ` + uploadColumns + `, COUNT(*) OVER () AS total
+		FROM file_store
+		WHERE owner_id = $1
+		ORDER BY created_at DESC, key
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := db.Pool.Query(ctx, query, ownerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	owned, err := pgx.CollectRows(rows, pgx.RowToStructByPos[ownedUpload])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	uploads := make([]common.Upload, 0, len(owned))
+	for _, upload := range owned {
+		uploads = append(uploads, upload.Upload)
+		total = upload.Total
+	}
+
+	return uploads, total, nil
+}
+
+type ownedUpload struct {
+	common.Upload
+	Total int
+}
+
+// GetUploadUsage counts the files a user has uploaded and their total size. Each upload counts
+// towards the total, even when its contents are shared with another upload.
+func (db *PostgresClient) GetUploadUsage(ctx context.Context, ownerID int) (int, int64, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(COALESCE(size, length(file), 0)), 0)
+		FROM file_store
+		WHERE owner_id = $1
+	`
+
+	var files int
+	var used int64
+	err := db.Pool.QueryRow(ctx, query, ownerID).Scan(&files, &used)
+
+	return files, used, err
+}
+
+type deletedUpload struct {
+	BlobKey *string
+	Key     string
+	SHA256  string
+	Stored  bool
+}
+
+// release drops the deleted upload's reference to its blob, returning the blob's key if it
+// should be deleted from storage. Uploads without a blob are stored under their own key, and
+// thumbnails of legacy uploads may have been stored there too, so that key is returned for both.
+func (u deletedUpload) release(ctx context.Context, tx pgx.Tx) (string, error) {
+	switch {
+	case !u.Stored, u.BlobKey == nil:
+		return u.Key, nil
+	default:
+		return releaseBlob(ctx, tx, u.SHA256)
+	}
+}
+
+// GetUploadCreatedAt retrieves the creation timestamp of an upload by its key.
+func (db *PostgresClient) GetUploadCreatedAt(
+	ctx context.Context,
+	key string,
+) (*time.Time, error) {
+	query := `
+		SELECT created_at
+		FROM file_store
+		WHERE key = $1
+	`
+
+	var createdAt time.Time
+	err := db.Pool.QueryRow(ctx, query, key).Scan(&createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &createdAt, nil
+}
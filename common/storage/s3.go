@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+	s3Timeout       = 5 * time.Minute
+)
+
+var (
+	errMissingBucket  = errors.New("s3 storage requires a bucket")
+	errNegativeOffset = errors.New("seek to a negative offset")
+)
+
+// S3Store keeps blobs in a bucket of an S3 compatible service such as AWS S3 or MinIO,
+// signing requests with AWS Signature Version 4.
+type S3Store struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+}
+
+// NewS3Store creates a store for the configured bucket. Without an endpoint AWS S3 is used.
+func NewS3Store(config common.StorageConfig) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, errMissingBucket
+	}
+
+	region := config.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+
+	return &S3Store{
+		client:    &http.Client{Timeout: s3Timeout},
+		endpoint:  parsed,
+		bucket:    config.Bucket,
+		region:    region,
+		accessKey: config.AccessKey,
+		secretKey: config.SecretKey,
+		pathStyle: config.PathStyle,
+	}, nil
+}
+
+func (s *S3Store) objectURL(key string) *url.URL {
+	object := *s.endpoint
+	object.RawQuery = ""
+
+	base := strings.TrimSuffix(object.Path, "/")
+	if s.pathStyle {
+		object.Path = base + "/" + s.bucket + "/" + key
+	} else {
+		object.Host = s.bucket + "." + object.Host
+		object.Path = base + "/" + key
+	}
+	object.RawPath = ""
+
+	return &object
+}
+
+func (s *S3Store) newRequest(
+	ctx context.Context,
+	method string,
+	key string,
+	body io.Reader,
+	contentType string,
+) (*http.Request, error) {
+	if !validKey(key) {
+		return nil, errInvalidKey
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	s.sign(request, unsignedPayload, time.Now())
+
+	return request, nil
+}
+
+func (s *S3Store) do(request *http.Request) (*http.Response, error) {
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close() //nolint:errcheck,gosec
+
+		return nil, ErrNotFound
+	}
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close() //nolint:errcheck,gosec
+
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", request.Method, request.URL.Path, response.Status, message)
+	}
+
+	return response, nil
+}
+
+// Put uploads the blob in a single request. S3 needs its size up front, so bodies of unknown size
+// are spooled to a temporary file first.
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		spool, err := os.CreateTemp("", "potat-upload-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(spool.Name()) //nolint:errcheck
+		defer spool.Close()           //nolint:errcheck
+
+		if size, err = io.Copy(spool, body); err != nil {
+			return err
+		}
+		if _, err = spool.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		body = spool
+	}
+
+	if size == 0 {
+		body = http.NoBody
+	}
+
+	request, err := s.newRequest(ctx, http.MethodPut, key, body, contentType)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// Get looks up the size of the blob, its contents are fetched with ranged requests once read.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	request, err := s.newRequest(ctx, http.MethodHead, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.do(request)
+	if err != nil {
+		return nil, err
+	}
+	if err = response.Body.Close(); err != nil {
+		return nil, err
+	}
+
+	return &s3Object{ctx: ctx, store: s, key: key, size: response.ContentLength}, nil
+}
+
+// Delete removes the blob from the bucket, S3 does not report missing objects on delete.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	request, err := s.newRequest(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+
+	response, err := s.do(request)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
+
+// s3Object reads a blob from S3, reopening it from the new offset after seeking.
+type s3Object struct {
+	ctx    context.Context //nolint:containedctx // Reads happen after Get returns.
+	store  *S3Store
+	body   io.ReadCloser
+	key    string
+	size   int64
+	offset int64
+}
+
+func (o *s3Object) Read(buffer []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+
+	if o.body == nil {
+		request, err := o.store.newRequest(o.ctx, http.MethodGet, o.key, nil, "")
+		if err != nil {
+			return 0, err
+		}
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", o.offset))
+
+		response, err := o.store.do(request)
+		if err != nil {
+			return 0, err
+		}
+		o.body = response.Body
+	}
+
+	read, err := o.body.Read(buffer)
+	o.offset += int64(read)
+
+	return read, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+
+	if offset < 0 {
+		return 0, errNegativeOffset
+	}
+
+	if offset != o.offset {
+		if err := o.Close(); err != nil {
+			return 0, err
+		}
+		o.offset = offset
+	}
+
+	return offset, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+
+	err := o.body.Close()
+	o.body = nil
+
+	return err
+}
+
+// sign adds an AWS Signature Version 4 authorization header covering the host and every header
+// already set on the request.
+func (s *S3Store) sign(request *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	scope := amzDate[:8] + "/" + s.region + "/s3/aws4_request"
+
+	request.Header.Del("Authorization")
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalPath(request.URL.Path),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.secretKey), amzDate[:8])
+	signingKey = hmacSHA256(signingKey, s.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write([]byte(data))
+
+	return hash.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	hash := sha256.Sum256([]byte(data))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// canonicalPath encodes each path segment the way S3 expects, leaving slashes as they are.
+func canonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent encodes everything except unreserved characters, as SigV4 requires.
+func uriEncode(value string) string {
+	var encoded strings.Builder
+	for _, char := range []byte(value) {
+		if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') ||
+			char == '-' || char == '_' || char == '.' || char == '~' {
+			encoded.WriteByte(char)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", char)
+		}
+	}
+
+	return encoded.String()
+}
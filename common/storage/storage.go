@@ -0,0 +1,87 @@
+// Package storage provides blob stores for uploaded files, keeping file contents out of Postgres.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+//nolint:revive
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+const defaultLocalPath = "./uploads"
+
+var (
+	// ErrNotFound is returned when a blob does not exist in the store.
+	ErrNotFound = errors.New("blob not found")
+
+	errInvalidKey = errors.New("invalid blob key")
+)
+
+// Store saves, retrieves and deletes blobs by key.
+type Store interface {
+	// Put stores size bytes read from body under key, replacing any existing blob.
+	// A negative size streams the body until EOF.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key for reading and seeking, returning ErrNotFound if it does not exist.
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob stored under key, deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New creates the store selected by the storage config, defaulting to the local filesystem.
+func New(config common.StorageConfig) (Store, error) {
+	switch config.Backend {
+	case "", BackendLocal:
+		path := config.Path
+		if path == "" {
+			path = defaultLocalPath
+		}
+
+		return NewLocalStore(path)
+	case BackendS3:
+		return NewS3Store(config)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.Backend)
+	}
+}
+
+// ThumbnailWidths are the widths thumbnails of uploaded images are generated at.
+var ThumbnailWidths = []int{64, 128, 256, 512, 1024} //nolint:gochecknoglobals // Constant list.
+
+// ThumbnailKey returns the key the thumbnail of a blob is stored under.
+func ThumbnailKey(blobKey string, width int) string {
+	return fmt.Sprintf("%s_w%d", blobKey, width)
+}
+
+// DeleteBlob deletes a blob along with any thumbnails generated from it.
+func DeleteBlob(ctx context.Context, store Store, blobKey string) error {
+	err := store.Delete(ctx, blobKey)
+	for _, width := range ThumbnailWidths {
+		err = errors.Join(err, store.Delete(ctx, ThumbnailKey(blobKey, width)))
+	}
+
+	return err
+}
+
+// Keys are generated by the uploader, but are still checked before being used as paths.
+func validKey(key string) bool {
+	if key == "" || key == "." || key == ".." {
+		return false
+	}
+
+	for _, char := range key {
+		if char == '/' || char == '\\' || char < ' ' {
+			return false
+		}
+	}
+
+	return true
+}
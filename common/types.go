@@ -17,6 +17,16 @@ const (
 	STV     Platforms = "STV"
 )
 
+// IsValid reports whether the platform is one supported by the bot.
+func (p Platforms) IsValid() bool {
+	switch p {
+	case TWITCH, DISCORD, KICK, STV:
+		return true
+	default:
+		return false
+	}
+}
+
 // PermissionLevel represents the permission level of a user interally with the api and bot.
 type PermissionLevel uint8
 
@@ -116,9 +126,12 @@ type Channel struct {
 type FilteredBlocks struct {
 	Users    *[]Block `json:"users"`
 	Commands *[]Block `json:"commands"`
+	Global   *[]Block `json:"global,omitempty"`
 }
 
 // Block represents a block connection between a user and a command or another user.
+// ID is the user who placed the block, BlockedUserID the user it applies to, and
+// CommandName the blocked command for COMMAND blocks.
 type Block struct {
 	ChannelID     string    `json:"channel_id"`
 	BlockType     BlockType `json:"block_type"`
@@ -137,6 +150,16 @@ const (
 	GlobalBlock  BlockType = "GLOBAL"
 )
 
+// IsValid reports whether the value is a known block type.
+func (b BlockType) IsValid() bool {
+	switch b {
+	case UserBlock, CommandBlock, GlobalBlock:
+		return true
+	default:
+		return false
+	}
+}
+
 // AddedByData represents the data of a user who added a channel.
 type AddedByData struct {
 	AddedAt  time.Time `json:"addedAt"`
@@ -179,6 +202,18 @@ type CommandSettings struct {
 	AllowBots        bool     `json:"allow_bots"`
 }
 
+// DefaultCommandSettings returns the settings a command has in a channel before they are ever changed.
+func DefaultCommandSettings(channelID, command string) CommandSettings {
+	return CommandSettings{
+		ChannelID:        channelID,
+		Command:          command,
+		Permission:       string(None),
+		UsersBlacklisted: []string{},
+		UsersWhitelisted: []string{},
+		IsEnabled:        true,
+	}
+}
+
 // PlatformOauth represents the OAuth token and metadata for a user on a specific platform, including.
 type PlatformOauth struct {
 	AddedAt      time.Time `json:"added_at"`
@@ -208,6 +243,7 @@ type ChannelCommand struct {
 	Delay          int       `json:"delay"`
 	UseCount       int       `json:"use_count"`
 	CommandID      int       `json:"command_id"`
+	Position       int       `json:"position"`
 	Reply          bool      `json:"reply"`
 	Whisper        bool      `json:"whisper"`
 	Announce       bool      `json:"announce"`
@@ -269,10 +305,219 @@ type PotatoData struct {
 	PotatoSettings
 }
 
+// LeaderboardSort is a statistic the potato leaderboard can be ranked by.
+type LeaderboardSort string
+
+//nolint:revive
+const (
+	SortPotatoCount  LeaderboardSort = "count"
+	SortPrestige     LeaderboardSort = "prestige"
+	SortSteals       LeaderboardSort = "steals"
+	SortTramples     LeaderboardSort = "tramples"
+	SortGambleWins   LeaderboardSort = "gamble_wins"
+	SortGambleLosses LeaderboardSort = "gamble_losses"
+	SortDuelWins     LeaderboardSort = "duel_wins"
+	SortDuelLosses   LeaderboardSort = "duel_losses"
+)
+
+// LeaderboardSorts lists every statistic the leaderboard can be ranked by.
+func LeaderboardSorts() []LeaderboardSort {
+	return []LeaderboardSort{
+		SortPotatoCount,
+		SortPrestige,
+		SortSteals,
+		SortTramples,
+		SortGambleWins,
+		SortGambleLosses,
+		SortDuelWins,
+		SortDuelLosses,
+	}
+}
+
+// IsValid reports whether the value is a known leaderboard sort.
+func (l LeaderboardSort) IsValid() bool {
+	switch l {
+	case SortPotatoCount, SortPrestige, SortSteals, SortTramples,
+		SortGambleWins, SortGambleLosses, SortDuelWins, SortDuelLosses:
+		return true
+	default:
+		return false
+	}
+}
+
+// LeaderboardEntry is a single user's standing on the potato leaderboard.
+type LeaderboardEntry struct {
+	Username          string `json:"username"`
+	Display           string `json:"display"`
+	Rank              int64  `json:"rank"`
+	Score             int64  `json:"score"`
+	ID                int    `json:"user_id"`
+	PotatoCount       int    `json:"potato_count"`
+	PotatoPrestige    int    `json:"potato_prestige"`
+	TheftCount        int    `json:"theft_count"`
+	TrampleCount      int    `json:"trample_count"`
+	GambleWinsTotal   int    `json:"gamble_wins_total"`
+	GambleLossesTotal int    `json:"gamble_losses_total"`
+	DuelWinsAmount    int    `json:"duel_wins_amount"`
+	DuelLossesAmount  int    `json:"duel_losses_amount"`
+}
+
+// ScoreBy returns the value of the given statistic for the entry.
+func (e LeaderboardEntry) ScoreBy(sort LeaderboardSort) int {
+	switch sort {
+	case SortPrestige:
+		return e.PotatoPrestige
+	case SortSteals:
+		return e.TheftCount
+	case SortTramples:
+		return e.TrampleCount
+	case SortGambleWins:
+		return e.GambleWinsTotal
+	case SortGambleLosses:
+		return e.GambleLossesTotal
+	case SortDuelWins:
+		return e.DuelWinsAmount
+	case SortDuelLosses:
+		return e.DuelLossesAmount
+	case SortPotatoCount:
+		return e.PotatoCount
+	default:
+		return e.PotatoCount
+	}
+}
+
+// Upload is the metadata of an uploaded file, its contents are kept in blob storage under BlobKey,
+// which is shared by every upload of identical content. Width and height are only set for images.
+// Legacy uploads still have their contents in Postgres until they are migrated. Uploads made
+// with the shared uploader key have no owner.
+type Upload struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	FileName  *string    `json:"file_name,omitempty"`
+	OwnerID   *int       `json:"owner_id,omitempty"`
+	BlobKey   string     `json:"-"`
+	Key       string     `json:"key"`
+	MimeType  string     `json:"mime_type"`
+	SHA256    string     `json:"sha256,omitempty"`
+	Size      int64      `json:"size"`
+	Width     int        `json:"width,omitempty"`
+	Height    int        `json:"height,omitempty"`
+	Legacy    bool       `json:"-"`
+}
+
+// UploadLimits are the largest file a user may upload, and the total size their uploads may take up.
+type UploadLimits struct {
+	MaxFileSize int64 `json:"max_file_size"`
+	Quota       int64 `json:"quota"`
+}
+
+// UploadUsage is how many files a user has uploaded and their total size, with the user's limits.
+type UploadUsage struct {
+	UploadLimits
+	Files int   `json:"files"`
+	Used  int64 `json:"used"`
+}
+
+// Remaining returns how many more bytes the user may upload before reaching their quota.
+func (u UploadUsage) Remaining() int64 {
+	return max(u.Quota-u.Used, 0)
+}
+
+// GetUploadLimits returns the upload limits of a permission level, blacklisted users may not upload.
+func GetUploadLimits(level PermissionLevel) UploadLimits {
+	switch {
+	case level >= ADMIN:
+		return UploadLimits{MaxFileSize: 100 << 20, Quota: 20 << 30}
+	case level == MOD:
+		return UploadLimits{MaxFileSize: 50 << 20, Quota: 5 << 30}
+	case level == USER:
+		return UploadLimits{MaxFileSize: 20 << 20, Quota: 1 << 30}
+	default:
+		return UploadLimits{}
+	}
+}
+
 // Redirect represents a URL redirect structure, typically used for OAuth flows.
+// Expiry, max clicks and owner are optional, Clicks only counts towards MaxClicks.
+// Preview links show every visitor an interstitial page with the destination first.
 type Redirect struct {
-	Key string `json:"key"`
-	URL string `json:"url"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks *int       `json:"max_clicks"`
+	OwnerID   *int       `json:"owner_id,omitempty"`
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	Clicks    int        `json:"clicks"`
+	Custom    bool       `json:"custom"`
+	Preview   bool       `json:"preview"`
+}
+
+// IsGone reports whether the redirect has expired or used up its clicks.
+func (r Redirect) IsGone() bool {
+	if r.ExpiresAt != nil && time.Now().After(*r.ExpiresAt) {
+		return true
+	}
+
+	return r.MaxClicks != nil && r.Clicks >= *r.MaxClicks
+}
+
+// DeniedDomain is a domain, including its subdomains, that redirects may not point to.
+type DeniedDomain struct {
+	CreatedAt time.Time `json:"created_at"`
+	Reason    *string   `json:"reason"`
+	AddedBy   *int      `json:"added_by"`
+	Domain    string    `json:"domain"`
+}
+
+// RedirectClick is a single hit on a redirect, recorded for analytics.
+type RedirectClick struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Key            string    `json:"key"`
+	ReferrerHost   string    `json:"referrer_host"`
+	Country        string    `json:"country"`
+	UserAgentClass string    `json:"user_agent_class"`
+	CacheHit       bool      `json:"cache_hit"`
+}
+
+// StatBucket is the number of clicks within a time bucket or for a given value.
+type StatBucket struct {
+	Time  *time.Time `json:"time,omitempty"`
+	Value string     `json:"value,omitempty"`
+	Count uint64     `json:"count"`
+}
+
+// RedirectStats is the aggregated click analytics of a redirect.
+type RedirectStats struct {
+	Key        string       `json:"key"`
+	Hourly     []StatBucket `json:"hourly"`
+	Daily      []StatBucket `json:"daily"`
+	Referrers  []StatBucket `json:"referrers"`
+	Countries  []StatBucket `json:"countries"`
+	UserAgents []StatBucket `json:"user_agents"`
+	Total      uint64       `json:"total"`
+	CacheHits  uint64       `json:"cache_hits"`
+}
+
+// HasteDocument is a stored hastebin text document. Documents may expire, and burn after read
+// documents are deleted the first time they are read. Language is empty for plain text, and
+// Parent is the key of the document this one was forked from.
+type HasteDocument struct {
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	Key           string     `json:"key"`
+	Content       string     `json:"data"`
+	Language      string     `json:"language,omitempty"`
+	Parent        string     `json:"parent,omitempty"`
+	BurnAfterRead bool       `json:"burn_after_read,omitempty"`
+}
+
+// HasteRevision describes a document in a chain of forks, without its content.
+type HasteRevision struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Key       string     `json:"key"`
+	Language  string     `json:"language,omitempty"`
+	Parent    string     `json:"parent,omitempty"`
 }
 
 // ErrorMessage represents a structure for error messages returned in API responses.
@@ -280,10 +525,20 @@ type ErrorMessage struct {
 	Message string `json:"message"`
 }
 
+// Pagination describes where a page of results sits within the full result set.
+// Cursor is set for cursor paginated endpoints and is passed back to fetch the next page.
+type Pagination struct {
+	Cursor string `json:"next_cursor,omitempty"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
 // GenericResponse represents a generic API response structure, which can include data and errors.
 type GenericResponse[T any] struct {
-	Data   *[]T            `json:"data"`
-	Errors *[]ErrorMessage `json:"errors,omitempty"`
+	Data       *[]T            `json:"data"`
+	Errors     *[]ErrorMessage `json:"errors,omitempty"`
+	Pagination *Pagination     `json:"pagination,omitempty"`
 }
 
 // TwitchValidation represents the structure of a Twitch OAuth validation response.
@@ -378,6 +633,16 @@ const (
 	Broadcaster UserRequires = "BROADCASTER"
 )
 
+// IsValid reports whether the value is a known user requirement.
+func (u UserRequires) IsValid() bool {
+	switch u {
+	case None, Subscriber, VIP, Mod, Ambassador, Broadcaster:
+		return true
+	default:
+		return false
+	}
+}
+
 // Flags represents a map of flags, where each flag is identified by a string key and can hold any type of value.
 type Flags map[string]interface{}
 
@@ -400,3 +665,17 @@ const (
 	BotVIP  BotCommandRequirements = "VIP"
 	BotMod  BotCommandRequirements = "MOD"
 )
+
+// APIKey represents a personal API key. The key itself is only ever returned once on creation,
+// only its hash is stored.
+type APIKey struct {
+	Created   time.Time  `json:"created"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	LastUsed  *time.Time `json:"last_used"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Key       string     `json:"key,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	ID        int        `json:"key_id"`
+	UserID    int        `json:"user_id"`
+}
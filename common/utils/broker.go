@@ -83,7 +83,7 @@ func (n *NatsClient) SetProxySocketFn(fn func([]byte) error) {
 
 // Publish sends a message to the specified topic on the NATS server.
 func (n *NatsClient) Publish(topic string, data []byte) error {
-	if n.Client == nil {
+	if n == nil || n.Client == nil {
 		return errNatsNotConnected
 	}
 
@@ -147,6 +147,7 @@ func BridgeRequest(
 	if err != nil {
 		return nil, fmt.Errorf("failed to publish request: %w", err)
 	}
+	defer nc.Close()
 
 	response, err := nc.Request(
 		"github.com/Potat-Industries/potat-api.job-request",
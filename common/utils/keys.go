@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync/atomic"
+)
+
+// Alphabets keys can be generated from.
+//
+//nolint:revive
+const (
+	AlphabetHex    = "0123456789abcdef"
+	AlphabetBase62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// ErrKeyspaceExhausted is returned when no free key could be found, even at the maximum key length.
+var ErrKeyspaceExhausted = errors.New("no free key available")
+
+var errInvalidAlphabet = errors.New("alphabet must have between 2 and 256 characters")
+
+// AlphabetByName returns the alphabet configured by name, defaulting to base62.
+func AlphabetByName(name string) string {
+	switch name {
+	case "hex":
+		return AlphabetHex
+	case "base62", "":
+		return AlphabetBase62
+	default:
+		return AlphabetBase62
+	}
+}
+
+// RandomKey generates a uniformly random key of the given length using characters from alphabet.
+func RandomKey(alphabet string, length int) (string, error) {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return "", errInvalidAlphabet
+	}
+
+	// Discard bytes past the largest multiple of the alphabet size to avoid modulo bias.
+	limit := 256 - 256%len(alphabet)
+	key := make([]byte, 0, length)
+	buffer := make([]byte, length*2)
+
+	for len(key) < length {
+		if _, err := rand.Read(buffer); err != nil {
+			return "", err
+		}
+
+		for _, b := range buffer {
+			if int(b) >= limit {
+				continue
+			}
+			key = append(key, alphabet[int(b)%len(alphabet)])
+			if len(key) == length {
+				break
+			}
+		}
+	}
+
+	return string(key), nil
+}
+
+// KeyInserter attempts to store a record under key, reporting false if the key is already taken.
+// It is expected to use INSERT ... ON CONFLICT DO NOTHING so checking and claiming a key is atomic.
+type KeyInserter func(ctx context.Context, key string) (bool, error)
+
+// KeyAllocator hands out unique random keys. When allocations keep colliding the keyspace is
+// considered crowded and the key length grows, up to MaxLength.
+type KeyAllocator struct {
+	alphabet  string
+	length    atomic.Int64
+	maxLength int
+	attempts  int
+}
+
+// NewKeyAllocator creates a key allocator starting at length characters from alphabet. The length
+// grows by one after attempts consecutive collisions, never past maxLength.
+func NewKeyAllocator(alphabet string, length, maxLength, attempts int) *KeyAllocator {
+	allocator := &KeyAllocator{
+		alphabet:  alphabet,
+		maxLength: max(length, maxLength),
+		attempts:  max(attempts, 1),
+	}
+	allocator.length.Store(int64(length))
+
+	return allocator
+}
+
+// Length returns the key length currently being allocated.
+func (a *KeyAllocator) Length() int {
+	return int(a.length.Load())
+}
+
+// Allocate generates keys and passes them to insert until one is stored, returning that key.
+func (a *KeyAllocator) Allocate(ctx context.Context, insert KeyInserter) (string, error) {
+	length := a.Length()
+
+	for {
+		for range a.attempts {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+
+			key, err := RandomKey(a.alphabet, length)
+			if err != nil {
+				return "", err
+			}
+
+			inserted, err := insert(ctx, key)
+			if err != nil {
+				return "", err
+			}
+
+			if inserted {
+				return key, nil
+			}
+		}
+
+		if length >= a.maxLength {
+			return "", ErrKeyspaceExhausted
+		}
+
+		a.length.CompareAndSwap(int64(length), int64(length+1))
+		length = a.Length()
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QRFormat is the image format a QR code is rendered as.
+type QRFormat string
+
+//nolint:revive
+const (
+	QRFormatPNG QRFormat = "png"
+	QRFormatSVG QRFormat = "svg"
+)
+
+//nolint:revive
+const (
+	DefaultQRSize = 256
+	MinQRSize     = 64
+	MaxQRSize     = 1024
+)
+
+var (
+	errInvalidQRFormat = errors.New("format must be png or svg")
+	errInvalidQRSize   = fmt.Errorf("size must be a number between %d and %d", MinQRSize, MaxQRSize)
+	errInvalidQRLevel  = errors.New("level must be one of L, M, Q or H")
+)
+
+// QROptions controls how a QR code is rendered.
+type QROptions struct {
+	Format QRFormat
+	Size   int
+	Level  qrcode.RecoveryLevel
+}
+
+// ParseQROptions reads the format, size and error correction level query parameters,
+// defaulting to a 256 pixel PNG with medium error correction.
+func ParseQROptions(query url.Values) (QROptions, error) {
+	options := QROptions{
+		Format: QRFormatPNG,
+		Size:   DefaultQRSize,
+		Level:  qrcode.Medium,
+	}
+
+	switch format := QRFormat(strings.ToLower(query.Get("format"))); format {
+	case "":
+	case QRFormatPNG, QRFormatSVG:
+		options.Format = format
+	default:
+		return options, errInvalidQRFormat
+	}
+
+	if size := query.Get("size"); size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil || parsed < MinQRSize || parsed > MaxQRSize {
+			return options, errInvalidQRSize
+		}
+		options.Size = parsed
+	}
+
+	switch strings.ToUpper(query.Get("level")) {
+	case "":
+	case "L":
+		options.Level = qrcode.Low
+	case "M":
+		options.Level = qrcode.Medium
+	case "Q":
+		options.Level = qrcode.High
+	case "H":
+		options.Level = qrcode.Highest
+	default:
+		return options, errInvalidQRLevel
+	}
+
+	return options, nil
+}
+
+// CacheKey returns the Redis key a QR code for content rendered with these options is cached under.
+func (o QROptions) CacheKey(content string) string {
+	return fmt.Sprintf("qr:%s:%d:%d:%s", o.Format, o.Size, o.Level, content)
+}
+
+// ContentType returns the MIME type of QR codes rendered with these options.
+func (o QROptions) ContentType() string {
+	if o.Format == QRFormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// RenderQR encodes content as a QR code image.
+func RenderQR(content string, options QROptions) ([]byte, error) {
+	code, err := qrcode.New(content, options.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Format == QRFormatSVG {
+		return renderQRSVG(code.Bitmap(), options.Size), nil
+	}
+
+	return code.PNG(options.Size)
+}
+
+// Draws each row of dark modules as horizontal runs to keep the path short.
+func renderQRSVG(bitmap [][]bool, size int) []byte {
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	modules := len(bitmap)
+
+	return fmt.Appendf(nil,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+			`<rect width="100%%" height="100%%" fill="#fff"/><path fill="#000" d="%s"/></svg>`,
+		size, size, modules, modules, path.String(),
+	)
+}
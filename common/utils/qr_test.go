@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+func TestQR__ParseQROptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    QROptions
+		wantErr bool
+	}{
+		{"", QROptions{Format: QRFormatPNG, Size: DefaultQRSize, Level: qrcode.Medium}, false},
+		{"format=SVG&size=512&level=h", QROptions{Format: QRFormatSVG, Size: 512, Level: qrcode.Highest}, false},
+		{"format=gif", QROptions{}, true},
+		{"size=10", QROptions{}, true},
+		{"size=big", QROptions{}, true},
+		{"level=X", QROptions{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			query, _ := url.ParseQuery(tc.query)
+			options, err := ParseQROptions(query)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && options != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, options)
+			}
+		})
+	}
+}
+
+func TestQR__RenderQR(t *testing.T) {
+	png, err := RenderQR("https://example.com/abc123", QROptions{Format: QRFormatPNG, Size: 128})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Error("Expected a PNG image")
+	}
+
+	svg, err := RenderQR("https://example.com/abc123", QROptions{Format: QRFormatSVG, Size: 128})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(svg), "<svg") || !strings.Contains(string(svg), `width="128"`) {
+		t.Errorf("Expected a 128 pixel SVG image, got %q", svg[:min(len(svg), 120)])
+	}
+}
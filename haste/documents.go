@@ -0,0 +1,129 @@
+package haste
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/utils"
+	"github.com/gorilla/mux"
+)
+
+var errInvalidExpiry = errors.New("expires must be a duration such as 30m, 12h or 7d, of at most 365d")
+
+// createdDocument is returned once when a document is created, the deletion token can't be recovered later.
+type createdDocument struct {
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	Key           string     `json:"key"`
+	DeleteToken   string     `json:"delete_token"`
+	Language      string     `json:"language,omitempty"`
+	Parent        string     `json:"parent,omitempty"`
+	BurnAfterRead bool       `json:"burn_after_read,omitempty"`
+}
+
+// parseExpiry turns a duration such as 30m, 12h or 7d into an expiry time, an empty value never expires.
+func parseExpiry(value string) (*time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == "never" {
+		return nil, nil //nolint:nilnil
+	}
+
+	var duration time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil {
+			return nil, errInvalidExpiry
+		}
+		duration = time.Duration(count) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, errInvalidExpiry
+		}
+		duration = parsed
+	}
+
+	if duration <= 0 || duration > maxExpiry {
+		return nil, errInvalidExpiry
+	}
+
+	expiresAt := time.Now().Add(duration)
+
+	return &expiresAt, nil
+}
+
+func parseBool(value string) bool {
+	parsed, err := strconv.ParseBool(value)
+
+	return err == nil && parsed
+}
+
+func hashDeleteToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// newDeleteToken returns a random deletion token and the hash stored in its place.
+func newDeleteToken() (string, string, error) {
+	token, err := utils.RandomKey(utils.AlphabetBase62, 32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, hashDeleteToken(token), nil
+}
+
+func (h *hastebin) handleDelete(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["id"]
+
+	token := request.Header.Get("X-Delete-Token")
+	if token == "" {
+		token = request.URL.Query().Get("token")
+	}
+	if token == "" {
+		http.Error(writer, "Deletion token required", http.StatusUnauthorized)
+
+		return
+	}
+
+	stored, err := h.postgres.GetHasteDeleteToken(request.Context(), key)
+	if errors.Is(err, db.ErrPostgresNoRows) {
+		http.Error(writer, "Document not found", http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		logger.Warn.Println("Failed to get deletion token: ", err)
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+
+		return
+	}
+
+	if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(hashDeleteToken(token))) != 1 {
+		http.Error(writer, "Invalid deletion token", http.StatusForbidden)
+
+		return
+	}
+
+	if _, err = h.postgres.DeleteHaste(request.Context(), key); err != nil {
+		logger.Warn.Println("Failed to delete document: ", err)
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+
+		return
+	}
+
+	if err = h.redis.Del(request.Context(), db.HasteCacheKey(key), db.HasteHTMLCacheKey(key)).Err(); err != nil {
+		logger.Warn.Println("Failed to uncache document: ", err)
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
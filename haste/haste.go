@@ -2,9 +2,11 @@
 package haste
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Potat-Industries/potat-api/api/middleware"
 	"github.com/Potat-Industries/potat-api/common"
@@ -29,14 +32,28 @@ const createTable = `
 		source TEXT default 'potatbotat',
 		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
+	ALTER TABLE haste
+		ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP,
+		ADD COLUMN IF NOT EXISTS burn_after_read BOOLEAN DEFAULT FALSE NOT NULL,
+		ADD COLUMN IF NOT EXISTS delete_token CHAR(64),
+		ADD COLUMN IF NOT EXISTS language VARCHAR(32),
+		ADD COLUMN IF NOT EXISTS parent_key VARCHAR(32);
+	CREATE INDEX IF NOT EXISTS haste_expires_at_idx ON haste (expires_at) WHERE expires_at IS NOT NULL;
 `
 
+const (
+	maxCacheDuration = time.Hour
+	maxExpiry        = 365 * 24 * time.Hour
+	defaultMaxSize   = 1 << 20 // 1MB
+)
+
 type hastebin struct {
-	server    *http.Server
-	router    *mux.Router
-	postgres  *db.PostgresClient
-	redis     *db.RedisClient
-	keyLength int
+	server   *http.Server
+	router   *mux.Router
+	postgres *db.PostgresClient
+	redis    *db.RedisClient
+	keys     *utils.KeyAllocator
+	maxSize  int64
 }
 
 // StartServing will start the Haste server on the configured port.
@@ -52,23 +69,27 @@ func StartServing(
 	}
 
 	haste := hastebin{
-		keyLength: 6,
-		postgres:  postgres,
-		redis:     redis,
+		postgres: postgres,
+		redis:    redis,
+		maxSize:  defaultMaxSize,
 	}
 
 	router := mux.NewRouter()
 
-	limiter := middleware.NewRateLimiter(100, 1*time.Minute, redis)
+	limiter := middleware.NewRateLimiter("haste", config.RateLimits, redis)
 	router.Use(middleware.LogRequest(metrics))
-	router.Use(limiter)
+	router.Use(limiter.Policy("default", common.RateLimitPolicy{Limit: 100, Window: 60}))
 
 	staticPath := haste.loadStaticFilePath()
 	staticFiles := haste.loadStaticFiles(staticPath)
 
 	router.HandleFunc("/raw/{id}", haste.handleGetRaw).Methods(http.MethodGet)
+	router.HandleFunc("/html/{id}", haste.handleGetHTML).Methods(http.MethodGet)
 	router.HandleFunc("/documents", haste.handlePost).Methods(http.MethodPost)
 	router.HandleFunc("/documents/{id}", haste.handleGet).Methods(http.MethodGet)
+	router.HandleFunc("/documents/{id}", haste.handleDelete).Methods(http.MethodDelete)
+	router.HandleFunc("/documents/{id}/history", haste.handleHistory).Methods(http.MethodGet)
+	router.HandleFunc("/diff/{a}/{b}", haste.handleDiff).Methods(http.MethodGet)
 	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if _, exists := staticFiles[r.URL.Path]; !exists {
 			r.URL.Path = "/"
@@ -85,10 +106,16 @@ func StartServing(
 	}
 	haste.router = router
 
+	keyLength := 6
 	if config.Haste.KeyLength != 0 {
-		haste.keyLength = config.Haste.KeyLength
+		keyLength = config.Haste.KeyLength
+	}
+	if config.Haste.MaxSize > 0 {
+		haste.maxSize = config.Haste.MaxSize
 	}
 
+	haste.keys = utils.NewKeyAllocator(utils.AlphabetByName(config.Haste.KeyAlphabet), keyLength, keyLength+6, 8)
+
 	haste.postgres.CheckTableExists(ctx, createTable)
 
 	logger.Info.Printf("Haste listening on %s", haste.server.Addr)
@@ -105,8 +132,8 @@ func (h *hastebin) getRedis(ctx context.Context, key string) (string, error) {
 	return data, nil
 }
 
-func (h *hastebin) setRedis(ctx context.Context, key, data string) {
-	err := h.redis.SetEx(ctx, key, data, time.Hour).Err()
+func (h *hastebin) setRedis(ctx context.Context, key, data string, ttl time.Duration) {
+	err := h.redis.SetEx(ctx, key, data, ttl).Err()
 	if err != nil {
 		logger.Warn.Printf("Failed to cache document: %v", err)
 
@@ -145,6 +172,52 @@ func (h *hastebin) loadStaticFiles(staticPath string) map[string]bool {
 	return files
 }
 
+// loadDocument returns a document from the cache or Postgres, caching it until it expires.
+// Burn after read documents are never cached, they are gone once loaded.
+func (h *hastebin) loadDocument(ctx context.Context, key string) (*common.HasteDocument, bool, error) {
+	cacheKey := db.HasteCacheKey(key)
+
+	cache, err := h.getRedis(ctx, cacheKey)
+	if cache != "" && err == nil {
+		var document common.HasteDocument
+		if err = json.Unmarshal([]byte(cache), &document); err == nil {
+			return &document, true, nil
+		}
+		logger.Warn.Printf("Failed to decode cached document: %v", err)
+	}
+
+	document, err := h.postgres.GetHaste(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ttl, ok := documentCacheTTL(document); ok {
+		if data, err := json.Marshal(document); err == nil {
+			go h.setRedis(context.WithoutCancel(ctx), cacheKey, string(data), ttl)
+		}
+	}
+
+	return document, false, nil
+}
+
+// documentCacheTTL returns how long a document may be cached for, and whether it may be cached at all.
+func documentCacheTTL(document *common.HasteDocument) (time.Duration, bool) {
+	ttl := maxCacheDuration
+	if document.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(*document.ExpiresAt))
+	}
+
+	return ttl, !document.BurnAfterRead && ttl > time.Second
+}
+
+func cacheHeader(hit bool) string {
+	if hit {
+		return "HIT"
+	}
+
+	return "MISS"
+}
+
 func (h *hastebin) handleGet(writer http.ResponseWriter, request *http.Request) {
 	key := mux.Vars(request)["id"]
 	if key == "" {
@@ -153,33 +226,20 @@ func (h *hastebin) handleGet(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	cache, err := h.getRedis(request.Context(), key)
-	if cache != "" && err == nil {
-		writer.Header().Set("Content-Type", "application/json")
-		writer.Header().Set("X-Cache-Hit", "HIT")
-		writer.WriteHeader(http.StatusOK)
-		err = json.NewEncoder(writer).Encode(map[string]string{"key": key, "data": cache})
-		if err != nil {
-			logger.Warn.Println("Failed to write document: ", err)
+	document, hit, err := h.loadDocument(request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, db.ErrPostgresNoRows) {
+			logger.Warn.Printf("Failed to get document: %v", err)
 		}
-
-		return
-	}
-
-	data, err := h.postgres.GetHaste(request.Context(), key)
-	if err != nil || data == "" {
-		logger.Warn.Printf("Failed to get document: %v", err)
 		http.Error(writer, "Document not found", http.StatusNotFound)
 
 		return
 	}
 
-	go h.setRedis(request.Context(), key, data)
-
 	writer.Header().Set("Content-Type", "application/json")
-	writer.Header().Set("X-Cache-Hit", "MISS")
+	writer.Header().Set("X-Cache-Hit", cacheHeader(hit))
 	writer.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(writer).Encode(map[string]string{"key": key, "data": data})
+	err = json.NewEncoder(writer).Encode(document)
 	if err != nil {
 		logger.Warn.Println("Failed to write document: ", err)
 	}
@@ -197,34 +257,20 @@ func (h *hastebin) handleGetRaw(writer http.ResponseWriter, request *http.Reques
 		key = strings.Split(key, ".")[0]
 	}
 
-	cache, err := h.getRedis(request.Context(), key)
-	if cache != "" && err == nil {
-		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		writer.Header().Set("X-Cache-Hit", "HIT")
-		writer.WriteHeader(http.StatusOK)
-		_, err = writer.Write([]byte(cache))
-		if err != nil {
-			logger.Warn.Println("Failed to write document: ", err)
+	document, hit, err := h.loadDocument(request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, db.ErrPostgresNoRows) {
+			logger.Warn.Printf("Failed to get document: %v", err)
 		}
-
-		return
-	}
-
-	data, err := h.postgres.GetHaste(request.Context(), key)
-	if err != nil || data == "" {
-		logger.Warn.Printf("Failed to get document: %v", err)
 		http.Error(writer, "Document not found", http.StatusNotFound)
 
 		return
 	}
 
-	parentCtx := context.WithoutCancel(request.Context())
-	go h.setRedis(parentCtx, key, data)
-
 	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	writer.Header().Set("X-Cache-Hit", "MISS")
+	writer.Header().Set("X-Cache-Hit", cacheHeader(hit))
 	writer.WriteHeader(http.StatusOK)
-	_, err = writer.Write([]byte(data))
+	_, err = writer.Write([]byte(document.Content))
 	if err != nil {
 		logger.Warn.Println("Failed to write document: ", err)
 	}
@@ -232,6 +278,14 @@ func (h *hastebin) handleGetRaw(writer http.ResponseWriter, request *http.Reques
 
 // nolint:cyclop
 func (h *hastebin) handlePost(writer http.ResponseWriter, request *http.Request) {
+	tooLarge := fmt.Sprintf("Document exceeds the maximum size of %d bytes", h.maxSize)
+	if request.ContentLength > h.maxSize {
+		http.Error(writer, tooLarge, http.StatusRequestEntityTooLarge)
+
+		return
+	}
+	request.Body = http.MaxBytesReader(writer, request.Body, h.maxSize)
+
 	err := request.ParseForm()
 	if err != nil {
 		http.Error(writer, "Error parsing form", http.StatusBadRequest)
@@ -241,6 +295,13 @@ func (h *hastebin) handlePost(writer http.ResponseWriter, request *http.Request)
 
 	body, err := io.ReadAll(request.Body)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(writer, tooLarge, http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
 		logger.Warn.Println("Error reading request body: ", err)
 		http.Error(writer, "Error reading request body", http.StatusInternalServerError)
 
@@ -286,15 +347,48 @@ func (h *hastebin) handlePost(writer http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	key, err := h.chooseKey(request.Context())
+	// Postgres can't convert_from documents that aren't valid UTF-8 or contain NUL bytes,
+	// so they could be stored but never read back.
+	if !utf8.Valid(body) || bytes.IndexByte(body, 0) != -1 {
+		http.Error(writer, "Document must be valid UTF-8 text", http.StatusBadRequest)
+
+		return
+	}
+
+	parent, ok := h.loadParent(writer, request)
+	if !ok {
+		return
+	}
+
+	expiresAt, err := parseExpiry(request.Form.Get("expires"))
 	if err != nil {
-		logger.Warn.Println("Failed to generate key: ", err)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	token, tokenHash, err := newDeleteToken()
+	if err != nil {
+		logger.Warn.Println("Failed to generate deletion token: ", err)
 		http.Error(writer, "Internal server error", http.StatusInternalServerError)
 
 		return
 	}
 
-	err = h.postgres.NewHaste(request.Context(), key, body, request.RemoteAddr)
+	document := common.HasteDocument{
+		ExpiresAt:     expiresAt,
+		BurnAfterRead: parseBool(request.Form.Get("burn")),
+		Language:      chooseLanguage(request, body, parent),
+	}
+	if parent != nil {
+		document.Parent = parent.Key
+	}
+
+	_, err = h.keys.Allocate(request.Context(), func(ctx context.Context, key string) (bool, error) {
+		document.Key = key
+
+		return h.postgres.NewHaste(ctx, &document, body, request.RemoteAddr, tokenHash)
+	})
 	if err != nil {
 		logger.Warn.Println("Failed to save document: ", err)
 		http.Error(writer, "Internal server error", http.StatusInternalServerError)
@@ -304,26 +398,15 @@ func (h *hastebin) handlePost(writer http.ResponseWriter, request *http.Request)
 
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(writer).Encode(map[string]string{"key": key})
+	err = json.NewEncoder(writer).Encode(createdDocument{
+		Key:           document.Key,
+		Language:      document.Language,
+		Parent:        document.Parent,
+		DeleteToken:   token,
+		ExpiresAt:     document.ExpiresAt,
+		BurnAfterRead: document.BurnAfterRead,
+	})
 	if err != nil {
 		logger.Warn.Println("Failed to write response: ", err)
 	}
 }
-
-func (h *hastebin) chooseKey(ctx context.Context) (string, error) {
-	for {
-		key, err := utils.RandomString(h.keyLength)
-		if err != nil {
-			return "", err
-		}
-
-		data, err := h.postgres.GetHaste(ctx, key)
-		if err != nil && !errors.Is(err, db.ErrPostgresNoRows) {
-			return "", err
-		}
-
-		if data == "" {
-			return key, nil
-		}
-	}
-}
@@ -0,0 +1,113 @@
+package haste
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+func TestHaste__RejectsInvalidDocuments(t *testing.T) {
+	haste := hastebin{maxSize: 16}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"too large", strings.Repeat("a", 17), http.StatusRequestEntityTooLarge},
+		{"invalid utf-8", "hello \xff\xfe", http.StatusBadRequest},
+		{"nul byte", "hello\x00world", http.StatusBadRequest},
+		{"empty", "", http.StatusLengthRequired},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(tc.body))
+			request.Header.Set("Content-Type", "text/plain")
+			recorder := httptest.NewRecorder()
+
+			haste.handlePost(recorder, request)
+
+			if recorder.Code != tc.expected {
+				t.Errorf("Expected status %d, got %d", tc.expected, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestHaste__RejectsOversizedStream(t *testing.T) {
+	haste := hastebin{maxSize: 16}
+
+	// Without a Content-Length the limit has to be enforced while reading.
+	request := httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(strings.Repeat("a", 64)))
+	request.ContentLength = -1
+	request.Header.Set("Content-Type", "text/plain")
+	recorder := httptest.NewRecorder()
+
+	haste.handlePost(recorder, request)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, recorder.Code)
+	}
+}
+
+func TestHaste__ResolveLanguage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"go", "go"},
+		{".py", "python"},
+		{"Python", "python"},
+		{"main.rs", "rust"},
+		{"txt", ""},
+		{"definitely-not-a-language", ""},
+		{"", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := resolveLanguage(tc.input); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHaste__RenderHighlighted(t *testing.T) {
+	document := &common.HasteDocument{Key: "abc123", Content: "package main\n\nfunc main() {}\n"}
+
+	page, err := renderHighlighted(document, "go")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"<title>abc123.go</title>", `id="L1"`, "package main"} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("Expected page to contain %q", expected)
+		}
+	}
+}
+
+func TestHaste__UnifiedDiff(t *testing.T) {
+	from := &common.HasteDocument{Key: "a", Content: "one\ntwo\nthree"}
+	to := &common.HasteDocument{Key: "b", Content: "one\n2\nthree\nfour\n"}
+
+	diff, err := unifiedDiff(from, to)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{"--- a\t", "+++ b\t", "-two\n", "+2\n", "+four\n", " three\n"} {
+		if !strings.Contains(diff, expected) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", expected, diff)
+		}
+	}
+
+	if same, _ := unifiedDiff(from, from); same != "" {
+		t.Errorf("Expected no diff between identical documents, got:\n%s", same)
+	}
+}
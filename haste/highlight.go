@@ -0,0 +1,246 @@
+package haste
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxDetectSize     = 8 << 10 // 8KB
+	maxDescription    = 200
+	highlightStyle    = "solarized-dark"
+	maxLanguageLength = 32
+)
+
+const highlightTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>{{.Title}}</title>
+	<meta property="og:title" content="{{.Title}}">
+	<meta property="og:description" content="{{.Description}}">
+	<meta property="og:type" content="article">
+	<style>
+		body { margin: 0; background: #002b36; }
+		pre { margin: 0; padding: 1rem; font-size: 14px; overflow-x: auto; }
+		{{.CSS}}
+	</style>
+</head>
+<body>
+{{.Code}}
+</body>
+</html>
+`
+
+var highlightPage = template.Must( //nolint:gochecknoglobals // Parsed once at startup.
+	template.New("highlight").Parse(highlightTemplate),
+)
+
+type highlightedDocument struct {
+	Title       string
+	Description string
+	CSS         template.CSS
+	Code        template.HTML
+}
+
+// languageName returns the name a lexer's language is stored under, or nothing for plain text.
+func languageName(lexer chroma.Lexer) string {
+	if lexer == nil || lexer == lexers.Fallback {
+		return ""
+	}
+
+	config := lexer.Config()
+	name := strings.ToLower(config.Name)
+	if len(config.Aliases) > 0 {
+		name = config.Aliases[0]
+	}
+
+	if name == "plaintext" || name == "text" || len(name) > maxLanguageLength {
+		return ""
+	}
+
+	return name
+}
+
+// resolveLanguage accepts a language name, alias, file extension or file name.
+func resolveLanguage(name string) string {
+	name = strings.TrimPrefix(strings.TrimSpace(name), ".")
+	if name == "" {
+		return ""
+	}
+
+	return languageName(lexers.Get(name))
+}
+
+func detectLanguage(content []byte) string {
+	sample := content[:min(len(content), maxDetectSize)]
+
+	return languageName(lexers.Analyse(string(sample)))
+}
+
+// chooseLanguage picks the language of a new document from the language field, the extension of
+// the filename field, the language of the document it was forked from, or by detecting it from the content.
+func chooseLanguage(request *http.Request, content []byte, parent *common.HasteDocument) string {
+	if language := resolveLanguage(request.Form.Get("language")); language != "" {
+		return language
+	}
+
+	if filename := request.Form.Get("filename"); filename != "" {
+		if language := resolveLanguage(path.Ext(filename)); language != "" {
+			return language
+		}
+		if language := resolveLanguage(path.Base(filename)); language != "" {
+			return language
+		}
+	}
+
+	if parent != nil && parent.Language != "" {
+		return parent.Language
+	}
+
+	return detectLanguage(content)
+}
+
+func describe(content string) string {
+	description := strings.Join(strings.Fields(content), " ")
+	if runes := []rune(description); len(runes) > maxDescription {
+		description = string(runes[:maxDescription-1]) + "…"
+	}
+
+	return description
+}
+
+func renderHighlighted(document *common.HasteDocument, language string) ([]byte, error) {
+	lexer := lexers.Fallback
+	if language != "" {
+		if found := lexers.Get(language); found != nil {
+			lexer = found
+		}
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, document.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	formatter := chromahtml.New(
+		chromahtml.WithClasses(true),
+		chromahtml.WithLineNumbers(true),
+		chromahtml.WithLinkableLineNumbers(true, "L"),
+		chromahtml.TabWidth(4),
+	)
+	style := styles.Get(highlightStyle)
+
+	var css, code bytes.Buffer
+	if err = formatter.WriteCSS(&css, style); err != nil {
+		return nil, err
+	}
+	if err = formatter.Format(&code, style, iterator); err != nil {
+		return nil, err
+	}
+
+	title := document.Key
+	if language != "" {
+		title += "." + language
+	}
+
+	var page bytes.Buffer
+	err = highlightPage.Execute(&page, highlightedDocument{
+		Title:       title,
+		Description: describe(document.Content),
+		CSS:         template.CSS(css.String()),   //nolint:gosec // Generated by chroma.
+		Code:        template.HTML(code.String()), //nolint:gosec // Escaped by chroma.
+	})
+
+	return page.Bytes(), err
+}
+
+// loadHighlighted returns the highlighted page of a document from the cache, or renders it and
+// caches it for as long as the document itself may be cached.
+func (h *hastebin) loadHighlighted(
+	ctx context.Context,
+	document *common.HasteDocument,
+	language string,
+) ([]byte, bool, error) {
+	cacheKey := db.HasteHTMLCacheKey(document.Key)
+
+	cache, err := h.redis.HGet(ctx, cacheKey, language).Bytes()
+	if err == nil && len(cache) > 0 {
+		return cache, true, nil
+	}
+
+	page, err := renderHighlighted(document, language)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ttl, ok := documentCacheTTL(document); ok {
+		go func(ctx context.Context) {
+			pipe := h.redis.TxPipeline()
+			pipe.HSet(ctx, cacheKey, language, page)
+			pipe.Expire(ctx, cacheKey, ttl)
+			if _, err := pipe.Exec(ctx); err != nil {
+				logger.Warn.Printf("Failed to cache highlighted document: %v", err)
+			}
+		}(context.WithoutCancel(ctx))
+	}
+
+	return page, false, nil
+}
+
+func (h *hastebin) handleGetHTML(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["id"]
+	if key == "" {
+		http.Error(writer, "Key not provided", http.StatusBadRequest)
+
+		return
+	}
+
+	// An extension overrides the stored language, the same as for /raw/{id}.ext.
+	key, extension, _ := strings.Cut(key, ".")
+
+	document, _, err := h.loadDocument(request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, db.ErrPostgresNoRows) {
+			logger.Warn.Printf("Failed to get document: %v", err)
+		}
+		http.Error(writer, "Document not found", http.StatusNotFound)
+
+		return
+	}
+
+	language := document.Language
+	if extension != "" {
+		language = resolveLanguage(extension)
+	}
+
+	page, hit, err := h.loadHighlighted(request.Context(), document, language)
+	if err != nil {
+		logger.Warn.Printf("Failed to highlight document: %v", err)
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("X-Cache-Hit", cacheHeader(hit))
+	writer.WriteHeader(http.StatusOK)
+	if _, err = writer.Write(page); err != nil {
+		logger.Warn.Println("Failed to write document: ", err)
+	}
+}
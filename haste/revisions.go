@@ -0,0 +1,169 @@
+package haste
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// maxDiffLines keeps diffs of large unrelated documents from taking too long to compute.
+const maxDiffLines = 5000
+
+var errDiffTooLarge = fmt.Errorf("documents with more than %d lines can't be diffed", maxDiffLines)
+
+// loadParent fetches the document a new document is forked from, writing a 400 or 404 response
+// if it can't be forked. Documents without a parent field return nil.
+func (h *hastebin) loadParent(writer http.ResponseWriter, request *http.Request) (*common.HasteDocument, bool) {
+	key := strings.TrimSpace(request.Form.Get("parent"))
+	if key == "" {
+		return nil, true
+	}
+
+	parent, err := h.postgres.GetHasteInfo(request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, db.ErrPostgresNoRows) {
+			logger.Warn.Printf("Failed to get parent document: %v", err)
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+
+			return nil, false
+		}
+		http.Error(writer, "Parent document not found", http.StatusNotFound)
+
+		return nil, false
+	}
+
+	if parent.BurnAfterRead {
+		http.Error(writer, "Burn after read documents can't be forked", http.StatusBadRequest)
+
+		return nil, false
+	}
+
+	return parent, true
+}
+
+// splitLines splits a document into lines for diffing, each ending with a newline.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+
+	return lines
+}
+
+// unifiedDiff returns the changes between two documents as a unified diff, which is empty
+// when both are the same.
+func unifiedDiff(from, to *common.HasteDocument) (string, error) {
+	fromLines, toLines := splitLines(from.Content), splitLines(to.Content)
+	if len(fromLines) > maxDiffLines || len(toLines) > maxDiffLines {
+		return "", errDiffTooLarge
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        fromLines,
+		B:        toLines,
+		FromFile: from.Key,
+		ToFile:   to.Key,
+		FromDate: from.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+		ToDate:   to.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+		Context:  3,
+	})
+}
+
+func (h *hastebin) handleHistory(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["id"]
+
+	history, err := h.postgres.GetHasteHistory(request.Context(), key)
+	if err != nil {
+		logger.Warn.Printf("Failed to get document history: %v", err)
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+
+		return
+	}
+
+	if len(history) == 0 {
+		http.Error(writer, "Document not found", http.StatusNotFound)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(writer).Encode(history); err != nil {
+		logger.Warn.Println("Failed to write document history: ", err)
+	}
+}
+
+// loadDiffable loads a document to diff, refusing burn after read documents since reading them
+// would delete them.
+func (h *hastebin) loadDiffable(
+	writer http.ResponseWriter,
+	request *http.Request,
+	key string,
+) (*common.HasteDocument, bool) {
+	info, err := h.postgres.GetHasteInfo(request.Context(), key)
+	if err == nil && info.BurnAfterRead {
+		http.Error(writer, "Burn after read documents can't be diffed", http.StatusBadRequest)
+
+		return nil, false
+	}
+
+	document, _, err := h.loadDocument(request.Context(), key)
+	if err != nil {
+		if !errors.Is(err, db.ErrPostgresNoRows) {
+			logger.Warn.Printf("Failed to get document: %v", err)
+		}
+		http.Error(writer, "Document not found", http.StatusNotFound)
+
+		return nil, false
+	}
+
+	return document, true
+}
+
+func (h *hastebin) handleDiff(writer http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+
+	from, ok := h.loadDiffable(writer, request, vars["a"])
+	if !ok {
+		return
+	}
+
+	to, ok := h.loadDiffable(writer, request, vars["b"])
+	if !ok {
+		return
+	}
+
+	diff, err := unifiedDiff(from, to)
+	if err != nil {
+		if errors.Is(err, errDiffTooLarge) {
+			http.Error(writer, err.Error(), http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		logger.Warn.Printf("Failed to diff documents: %v", err)
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	if _, err = writer.Write([]byte(diff)); err != nil {
+		logger.Warn.Println("Failed to write diff: ", err)
+	}
+}
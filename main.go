@@ -11,11 +11,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/Potat-Industries/potat-api/api"
+	_ "github.com/Potat-Industries/potat-api/api/routes/delete"
 	_ "github.com/Potat-Industries/potat-api/api/routes/get"
+	_ "github.com/Potat-Industries/potat-api/api/routes/patch"
 	_ "github.com/Potat-Industries/potat-api/api/routes/post"
 	"github.com/Potat-Industries/potat-api/common"
 	"github.com/Potat-Industries/potat-api/common/db"
@@ -29,6 +32,9 @@ import (
 
 var errPingTimeout = errors.New("ping timed out")
 
+// workerFlushTimeout bounds how long shutdown waits for background workers to write what they buffered.
+const workerFlushTimeout = 10 * time.Second
+
 func main() { //nolint:cyclop
 	logger.Info.Println("Starting Potat API...")
 
@@ -90,10 +96,13 @@ func main() { //nolint:cyclop
 		}()
 	}
 
+	// Background workers started by the servers, waited on at shutdown so they can flush.
+	var workers sync.WaitGroup
+
 	redirectsChan := make(chan error)
 	if config.Redirects.Enabled {
 		go func() {
-			redirectsChan <- redirects.StartServing(ctx, *config, postgres, redis, metrics)
+			redirectsChan <- redirects.StartServing(ctx, *config, postgres, redis, clickhouse, metrics, &workers)
 		}()
 	}
 
@@ -107,7 +116,7 @@ func main() { //nolint:cyclop
 	apiChan := make(chan error)
 	if config.API.Enabled {
 		go func() {
-			apiChan <- api.StartServing(*config, postgres, redis, clickhouse, metrics)
+			apiChan <- api.StartServing(ctx, *config, postgres, redis, clickhouse, nats, metrics)
 		}()
 	}
 
@@ -128,7 +137,13 @@ func main() { //nolint:cyclop
 		logger.Warn.Println("Shutdown requested...")
 	}
 
-	if config.API.Enabled {
+	// Stop background workers and give them time to flush what they have buffered.
+	cancel()
+	if !waitWithTimeout(&workers, workerFlushTimeout) {
+		logger.Warn.Println("Timed out waiting for background workers to flush")
+	}
+
+	if clickhouse != nil {
 		if err := clickhouse.Close(); err != nil {
 			logger.Error.Panicln("Failed closing Clickhouse connection", err)
 		}
@@ -144,6 +159,22 @@ func main() { //nolint:cyclop
 	logger.Warn.Println("Postgres connection closed")
 }
 
+// waitWithTimeout waits for a WaitGroup, reporting false if it did not finish within the timeout.
+func waitWithTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 func runWithTimeout(
 	ctx context.Context,
 	f func(ctx context.Context) error,
@@ -178,6 +209,7 @@ func initPostgres(ctx context.Context, config common.Config) *db.PostgresClient
 	if err != nil {
 		logger.Error.Panicln("Failed pinging Postgres", err)
 	}
+	postgres.MigrateSharedColumns(ctx)
 	logger.Info.Println("Postgres initialized")
 
 	return postgres
@@ -199,7 +231,7 @@ func initRedis(ctx context.Context, config common.Config) *db.RedisClient {
 }
 
 func initClickhouse(ctx context.Context, config common.Config) *db.ClickhouseClient {
-	if !config.API.Enabled && !config.Loops.Enabled {
+	if !config.API.Enabled && !config.Loops.Enabled && !config.Redirects.Enabled {
 		return nil
 	}
 
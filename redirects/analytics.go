@@ -0,0 +1,152 @@
+package redirects
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+)
+
+const (
+	clickBufferSize    = 10000
+	clickBatchSize     = 500
+	clickFlushInterval = 5 * time.Second
+)
+
+// clickRecorder buffers redirect clicks in memory and writes them to ClickHouse in batches,
+// so recording a click never delays the redirect itself.
+type clickRecorder struct {
+	clickhouse *db.ClickhouseClient
+	clicks     chan common.RedirectClick
+	workers    *sync.WaitGroup
+}
+
+// newClickRecorder starts a click recorder, which is added to workers until it has flushed its
+// buffered clicks after ctx is cancelled.
+func newClickRecorder(ctx context.Context, clickhouse *db.ClickhouseClient, workers *sync.WaitGroup) *clickRecorder {
+	recorder := &clickRecorder{
+		clickhouse: clickhouse,
+		clicks:     make(chan common.RedirectClick, clickBufferSize),
+		workers:    workers,
+	}
+
+	if clickhouse == nil {
+		logger.Warn.Println("Clickhouse not configured, redirect analytics are disabled")
+
+		return recorder
+	}
+
+	if err := clickhouse.EnsureRedirectClicks(ctx); err != nil {
+		logger.Error.Printf("Failed to create redirect clicks table: %v", err)
+	}
+
+	workers.Add(1)
+	go recorder.run(ctx)
+
+	return recorder
+}
+
+func (c *clickRecorder) record(request *http.Request, key string, cacheHit bool) {
+	if c.clickhouse == nil {
+		return
+	}
+
+	click := common.RedirectClick{
+		Timestamp:      time.Now(),
+		Key:            key,
+		ReferrerHost:   referrerHost(request.Referer()),
+		Country:        strings.ToUpper(request.Header.Get("CF-IPCountry")),
+		UserAgentClass: classifyUserAgent(request.UserAgent()),
+		CacheHit:       cacheHit,
+	}
+
+	select {
+	case c.clicks <- click:
+	default:
+		logger.Warn.Println("Redirect click buffer full, dropping click")
+	}
+}
+
+func (c *clickRecorder) run(ctx context.Context) {
+	defer c.workers.Done()
+
+	ticker := time.NewTicker(clickFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]common.RedirectClick, 0, clickBatchSize)
+	for {
+		select {
+		case click := <-c.clicks:
+			batch = append(batch, click)
+			if len(batch) >= clickBatchSize {
+				batch = c.flush(ctx, batch)
+			}
+		case <-ticker.C:
+			batch = c.flush(ctx, batch)
+		case <-ctx.Done():
+			for len(c.clicks) > 0 {
+				batch = append(batch, <-c.clicks)
+			}
+			c.flush(context.WithoutCancel(ctx), batch)
+
+			return
+		}
+	}
+}
+
+func (c *clickRecorder) flush(ctx context.Context, batch []common.RedirectClick) []common.RedirectClick {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := c.clickhouse.InsertRedirectClicks(ctx, batch); err != nil {
+		logger.Error.Printf("Failed to write %d redirect clicks: %v", len(batch), err)
+	}
+
+	return batch[:0]
+}
+
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(parsed.Hostname())
+}
+
+func classifyUserAgent(userAgent string) string {
+	agent := strings.ToLower(userAgent)
+
+	switch {
+	case agent == "":
+		return "unknown"
+	case strings.Contains(agent, "bot"),
+		strings.Contains(agent, "crawl"),
+		strings.Contains(agent, "spider"),
+		strings.Contains(agent, "preview"),
+		strings.Contains(agent, "facebookexternalhit"),
+		strings.Contains(agent, "curl"),
+		strings.Contains(agent, "wget"):
+		return "bot"
+	case strings.Contains(agent, "mobi"),
+		strings.Contains(agent, "android"),
+		strings.Contains(agent, "iphone"),
+		strings.Contains(agent, "ipad"):
+		return "mobile"
+	case strings.Contains(agent, "mozilla"):
+		return "desktop"
+	default:
+		return "other"
+	}
+}
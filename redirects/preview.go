@@ -0,0 +1,158 @@
+package redirects
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/gorilla/mux"
+)
+
+const previewTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<meta name="robots" content="noindex">
+	<meta property="og:title" content="Redirect to {{.Host}}">
+	<meta property="og:description" content="{{.URL}}">
+	<title>Redirect to {{.Host}}</title>
+	<style>
+		body { font-family: sans-serif; background: #18181b; color: #efeff1; display: flex; justify-content: center; }
+		main { max-width: 40rem; margin-top: 10vh; padding: 1.5rem; background: #1f1f23; border-radius: 8px; }
+		.url { word-break: break-all; font-family: monospace; padding: 0.5rem; background: #0e0e10; border-radius: 4px; }
+		.meta { color: #adadb8; font-size: 0.9rem; }
+		a.continue { display: inline-block; margin-top: 1rem; padding: 0.5rem 1rem; background: #9147ff; color: #fff;
+			border-radius: 4px; text-decoration: none; }
+	</style>
+</head>
+<body>
+	<main>
+		<h1>This link goes to {{.Host}}</h1>
+		<p class="url">{{.URL}}</p>
+		<p class="meta">Created {{.CreatedAt.UTC.Format "2006-01-02 15:04"}} UTC &middot; {{.Clicks}} clicks</p>
+		<a class="continue" href="{{.Continue}}" rel="noreferrer noopener">Continue to {{.Host}}</a>
+	</main>
+</body>
+</html>
+`
+
+var previewPage = template.Must( //nolint:gochecknoglobals // Parsed once at startup.
+	template.New("preview").Parse(previewTemplate),
+)
+
+// redirectPreview describes where a short link leads. Tooltip is formatted for the Chatterino link resolver.
+type redirectPreview struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	Host      string     `json:"host"`
+	Tooltip   string     `json:"tooltip"`
+	Continue  string     `json:"-"`
+	Clicks    uint64     `json:"clicks"`
+}
+
+// Whether the visitor asked to see where the link goes instead of being redirected.
+func wantsPreview(request *http.Request) bool {
+	switch strings.ToLower(request.URL.Query().Get("preview")) {
+	case "1", "true", "json":
+		return true
+	default:
+		return false
+	}
+}
+
+// Whether the visitor already went through the interstitial of a forced preview link.
+func confirmedPreview(request *http.Request) bool {
+	return request.URL.Query().Get("confirm") == "1"
+}
+
+func wantsPreviewJSON(request *http.Request) bool {
+	query := request.URL.Query()
+	if query.Get("preview") == "json" || query.Get("format") == "json" {
+		return true
+	}
+
+	if strings.HasPrefix(request.UserAgent(), "chatterino-api-cache") {
+		return true
+	}
+
+	accept := request.Header.Get("Accept")
+
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+func (r *redirects) getPreview(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["id"]
+	if key == "" {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	redirect, ok := r.loadRedirect(writer, request, key)
+	if !ok {
+		return
+	}
+
+	r.renderPreview(writer, request, redirect)
+}
+
+func (r *redirects) renderPreview(writer http.ResponseWriter, request *http.Request, redirect *common.Redirect) {
+	preview := redirectPreview{
+		Key:       redirect.Key,
+		URL:       r.cleanRedirectProtocolSoLinksActuallyWork(redirect.URL),
+		CreatedAt: redirect.CreatedAt,
+		ExpiresAt: redirect.ExpiresAt,
+		Clicks:    uint64(max(redirect.Clicks, 0)),
+		Continue:  "/" + url.PathEscape(redirect.Key) + "?confirm=1",
+	}
+
+	if target, err := url.Parse(preview.URL); err == nil {
+		preview.Host = target.Hostname()
+	}
+
+	if r.clickhouse != nil {
+		clicks, err := r.clickhouse.CountRedirectClicks(request.Context(), redirect.Key)
+		if err != nil {
+			logger.Warn.Printf("Failed to count clicks for redirect %s: %v", redirect.Key, err)
+		} else {
+			preview.Clicks = max(preview.Clicks, clicks)
+		}
+	}
+
+	preview.Tooltip = fmt.Sprintf(
+		`<div style="text-align: left;"><b>Redirect to %s</b><br>%s<br><b>Created:</b> %s<br><b>Clicks:</b> %d</div>`,
+		html.EscapeString(preview.Host),
+		html.EscapeString(preview.URL),
+		preview.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"),
+		preview.Clicks,
+	)
+
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("X-Robots-Tag", "noindex")
+
+	if wantsPreviewJSON(request) {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(writer).Encode(preview); err != nil {
+			logger.Warn.Printf("Failed to write redirect preview: %v", err)
+		}
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.WriteHeader(http.StatusOK)
+	if err := previewPage.Execute(writer, preview); err != nil {
+		logger.Warn.Printf("Failed to render redirect preview: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package redirects
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/utils"
+	"github.com/gorilla/mux"
+)
+
+// shortLink returns the public URL of a redirect, as encoded in its QR code.
+func (r *redirects) shortLink(request *http.Request, key string) string {
+	host := r.publicHost
+	if host == "" {
+		host = request.Host
+	}
+
+	return "https://" + host + "/" + key
+}
+
+func (r *redirects) getQR(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["id"]
+	if key == "" {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	options, err := utils.ParseQROptions(request.URL.Query())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	// Check the link is still live before serving a cached code, a live redirect is found in
+	// the redirect cache without touching Postgres.
+	var redirect *common.Redirect
+	if cached, err := r.getRedis(request.Context(), key); err != nil || cached == "" {
+		var ok bool
+		if redirect, ok = r.loadRedirect(writer, request, key); !ok {
+			return
+		}
+	}
+
+	content := r.shortLink(request, key)
+	cacheKey := options.CacheKey(content)
+
+	cache, err := r.getRedis(request.Context(), cacheKey)
+	if err == nil && cache != "" {
+		writer.Header().Set("Content-Type", options.ContentType())
+		writer.Header().Set("X-Cache-Hit", "HIT")
+		if _, err = writer.Write([]byte(cache)); err != nil {
+			logger.Warn.Printf("Failed to write QR code: %v", err)
+		}
+
+		return
+	}
+	writer.Header().Set("X-Cache-Hit", "MISS")
+
+	if redirect == nil {
+		var ok bool
+		if redirect, ok = r.loadRedirect(writer, request, key); !ok {
+			return
+		}
+	}
+
+	image, err := utils.RenderQR(content, options)
+	if err != nil {
+		logger.Error.Printf("Error rendering QR code: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	if ttl, ok := cacheTTL(redirect); ok {
+		parentCtx := context.WithoutCancel(request.Context())
+		go r.setRedis(parentCtx, cacheKey, string(image), ttl)
+	}
+
+	writer.Header().Set("Content-Type", options.ContentType())
+	if _, err = writer.Write(image); err != nil {
+		logger.Warn.Printf("Failed to write QR code: %v", err)
+	}
+}
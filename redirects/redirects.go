@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Potat-Industries/potat-api/api/middleware"
@@ -21,12 +22,27 @@ const createTable = `
 		key VARCHAR(9) PRIMARY KEY,
 		url VARCHAR(500) NOT NULL
 	);
+	ALTER TABLE url_redirects
+		ALTER COLUMN key TYPE VARCHAR(32),
+		ADD COLUMN IF NOT EXISTS owner_id INT,
+		ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP,
+		ADD COLUMN IF NOT EXISTS max_clicks INT,
+		ADD COLUMN IF NOT EXISTS clicks INT DEFAULT 0 NOT NULL,
+		ADD COLUMN IF NOT EXISTS custom BOOLEAN DEFAULT FALSE NOT NULL,
+		ADD COLUMN IF NOT EXISTS preview BOOLEAN DEFAULT FALSE NOT NULL,
+		ADD COLUMN IF NOT EXISTS created_at TIMESTAMP DEFAULT NOW() NOT NULL;
+	CREATE INDEX IF NOT EXISTS url_redirects_owner_id_idx ON url_redirects (owner_id);
 `
 
+const maxCacheDuration = time.Hour
+
 type redirects struct {
-	server   *http.Server
-	postgres *db.PostgresClient
-	redis    *db.RedisClient
+	server     *http.Server
+	postgres   *db.PostgresClient
+	redis      *db.RedisClient
+	clickhouse *db.ClickhouseClient
+	clicks     *clickRecorder
+	publicHost string
 }
 
 // StartServing will start the redirects server on the configured port.
@@ -35,22 +51,29 @@ func StartServing(
 	config common.Config,
 	postgres *db.PostgresClient,
 	redis *db.RedisClient,
+	clickhouse *db.ClickhouseClient,
 	metrics *utils.Metrics,
+	workers *sync.WaitGroup,
 ) error {
 	if config.Redirects.Host == "" || config.Redirects.Port == "" {
 		logger.Error.Fatal("Config: Redirect host and port must be set")
 	}
 
 	redirector := redirects{
-		postgres: postgres,
-		redis:    redis,
+		postgres:   postgres,
+		redis:      redis,
+		clickhouse: clickhouse,
+		clicks:     newClickRecorder(ctx, clickhouse, workers),
+		publicHost: config.Redirects.PublicHost,
 	}
 
 	router := mux.NewRouter()
 
-	limiter := middleware.NewRateLimiter(100, 1*time.Minute, redis)
+	limiter := middleware.NewRateLimiter("redirects", config.RateLimits, redis)
 	router.Use(middleware.LogRequest(metrics))
-	router.Use(limiter)
+	router.Use(limiter.Policy("default", common.RateLimitPolicy{Limit: 100, Window: 60}))
+	router.HandleFunc("/{id}+", redirector.getPreview).Methods(http.MethodGet)
+	router.HandleFunc("/{id}/qr", redirector.getQR).Methods(http.MethodGet)
 	router.HandleFunc("/{id}", redirector.getRedirect).Methods(http.MethodGet)
 
 	redirector.server = &http.Server{
@@ -68,8 +91,8 @@ func StartServing(
 	return redirector.server.ListenAndServe()
 }
 
-func (r *redirects) setRedis(ctx context.Context, key, data string) {
-	err := r.redis.SetEx(ctx, key, data, time.Hour).Err()
+func (r *redirects) setRedis(ctx context.Context, key, data string, ttl time.Duration) {
+	err := r.redis.SetEx(ctx, key, data, ttl).Err()
 	if err != nil {
 		logger.Error.Printf("Error caching redirect: %v", err)
 	}
@@ -84,13 +107,11 @@ func (r *redirects) getRedis(ctx context.Context, key string) (string, error) {
 	return data, nil
 }
 
+// Links are normalized when created, this only fills in a scheme for links stored without one.
 func (r *redirects) cleanRedirectProtocolSoLinksActuallyWork(url string) string {
-	if strings.HasPrefix(url, "https://") {
+	if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
 		return url
 	}
-	if strings.HasPrefix(url, "http://") {
-		return "https://" + strings.TrimPrefix(url, "http://")
-	}
 	if strings.HasPrefix(url, "//") {
 		return "https:" + url
 	}
@@ -98,6 +119,46 @@ func (r *redirects) cleanRedirectProtocolSoLinksActuallyWork(url string) string
 	return "https://" + url
 }
 
+// cacheTTL returns how long data derived from a redirect may be cached, which is never past its
+// expiry, and not at all for links with a click limit.
+func cacheTTL(redirect *common.Redirect) (time.Duration, bool) {
+	ttl := maxCacheDuration
+	if redirect.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(*redirect.ExpiresAt))
+	}
+
+	return ttl, redirect.MaxClicks == nil && ttl > time.Second
+}
+
+// loadRedirect fetches a redirect, writing a 404 or 410 response if it can't be followed.
+func (r *redirects) loadRedirect(
+	writer http.ResponseWriter,
+	request *http.Request,
+	key string,
+) (*common.Redirect, bool) {
+	redirect, err := r.postgres.GetRedirect(request.Context(), key)
+	if err != nil {
+		if errors.Is(err, db.ErrPostgresNoRows) {
+			http.NotFound(writer, request)
+
+			return nil, false
+		}
+
+		logger.Error.Printf("Error fetching redirect: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return nil, false
+	}
+
+	if redirect.IsGone() {
+		http.Error(writer, "This link has expired", http.StatusGone)
+
+		return nil, false
+	}
+
+	return redirect, true
+}
+
 func (r *redirects) getRedirect(writer http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	key := vars["id"]
@@ -107,34 +168,58 @@ func (r *redirects) getRedirect(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
+	if wantsPreview(request) {
+		r.getPreview(writer, request)
+
+		return
+	}
+
 	cache, err := r.getRedis(request.Context(), key)
 	if err == nil && cache != "" {
 		writer.Header().Set("X-Cache-Hit", "HIT")
+		r.clicks.record(request, key, true)
 		http.Redirect(writer, request, cache, http.StatusSeeOther)
 
 		return
 	}
 	writer.Header().Set("X-Cache-Hit", "MISS")
 
-	redirect, err := r.postgres.GetRedirectByKey(request.Context(), key)
-	if err != nil {
-		if errors.Is(err, db.ErrPostgresNoRows) {
-			http.NotFound(writer, request)
-
-			return
-		}
+	redirect, ok := r.loadRedirect(writer, request, key)
+	if !ok {
+		return
+	}
 
-		logger.Error.Printf("Error fetching redirect: %v", err)
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+	if redirect.Preview && !confirmedPreview(request) {
+		r.renderPreview(writer, request, redirect)
 
 		return
 	}
 
-	redirect = r.cleanRedirectProtocolSoLinksActuallyWork(redirect)
+	// Links with a click limit are never cached, every click has to be counted in Postgres
+	if redirect.MaxClicks != nil {
+		redirect, err = r.postgres.UseLimitedRedirect(request.Context(), key)
+		if err != nil {
+			if errors.Is(err, db.ErrPostgresNoRows) {
+				http.Error(writer, "This link has expired", http.StatusGone)
 
-	parentCtx := context.WithoutCancel(request.Context())
-	go r.setRedis(parentCtx, key, redirect)
+				return
+			}
+
+			logger.Error.Printf("Error counting redirect click: %v", err)
+			http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	target := r.cleanRedirectProtocolSoLinksActuallyWork(redirect.URL)
+
+	// Forced preview links are never cached either, the cache can only store plain redirects
+	if ttl, ok := cacheTTL(redirect); ok && !redirect.Preview {
+		parentCtx := context.WithoutCancel(request.Context())
+		go r.setRedis(parentCtx, key, target, ttl)
+	}
 
-	request.Header.Set("X-Cache-Hit", "MISS")
-	http.Redirect(writer, request, redirect, http.StatusSeeOther)
+	r.clicks.record(request, key, false)
+	http.Redirect(writer, request, target, http.StatusSeeOther)
 }
@@ -1,6 +1,8 @@
 package redirects
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -13,7 +15,7 @@ func TestRedirects__CheckProtocolFormatAfterProtocolReformat(t *testing.T) {
 		expected string
 	}{
 		{"https://google.com", "https://google.com"},
-		{"http://google.com", "https://google.com"},
+		{"http://google.com", "http://google.com"},
 		{"//google.com", "https://google.com"},
 		{"google.com", "https://google.com"},
 	}
@@ -21,8 +23,8 @@ func TestRedirects__CheckProtocolFormatAfterProtocolReformat(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.input, func(t *testing.T) {
 			cleanedURL := redirector.cleanRedirectProtocolSoLinksActuallyWork(tc.input)
-			if !strings.HasPrefix(cleanedURL, "https://") {
-				t.Errorf("Expected cleaned URL to start with 'https://', got %q", cleanedURL)
+			if !strings.HasPrefix(cleanedURL, "https://") && !strings.HasPrefix(cleanedURL, "http://") {
+				t.Errorf("Expected cleaned URL to have a scheme, got %q", cleanedURL)
 			}
 			if cleanedURL != tc.expected {
 				t.Errorf("Expected %q, got %q", tc.expected, cleanedURL)
@@ -30,3 +32,54 @@ func TestRedirects__CheckProtocolFormatAfterProtocolReformat(t *testing.T) {
 		})
 	}
 }
+
+func TestRedirects__ClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "unknown"},
+		{"Mozilla/5.0 (compatible; Discordbot/2.0; +https://discordapp.com)", "bot"},
+		{"curl/8.4.0", "bot"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", "mobile"},
+		{"Mozilla/5.0 (Linux; Android 14) Mobile Safari/537.36", "mobile"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36", "desktop"},
+		{"chatterino/2.5", "other"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := classifyUserAgent(tc.input); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedirects__WantsPreviewJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		userAgent string
+		accept    string
+		expected  bool
+	}{
+		{"browser", "/abc+", "Mozilla/5.0", "text/html,application/xhtml+xml,*/*", false},
+		{"query", "/abc?preview=json", "Mozilla/5.0", "", true},
+		{"format", "/abc+?format=json", "", "", true},
+		{"accept", "/abc+", "curl/8.0", "application/json", true},
+		{"chatterino", "/abc+", "chatterino-api-cache/1.0 link-resolver", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			request.Header.Set("User-Agent", tc.userAgent)
+			request.Header.Set("Accept", tc.accept)
+
+			if got := wantsPreviewJSON(request); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
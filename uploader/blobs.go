@@ -0,0 +1,51 @@
+package uploader
+
+import (
+	"context"
+
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
+	"github.com/Potat-Industries/potat-api/common/utils"
+)
+
+// Blobs get random keys of their own, since a blob outlives the upload it was first stored for.
+const blobKeyLength = 32
+
+func newBlobKey() (string, error) {
+	return utils.RandomKey(utils.AlphabetBase62, blobKeyLength)
+}
+
+// claimBlob references the stored blob for content with the given hash, switching to an existing
+// blob with identical content if there is one and deleting the duplicate.
+func (u *uploader) claimBlob(ctx context.Context, sha256, blobKey string, size int64) (string, error) {
+	claimed, err := u.postgres.ClaimUploadBlob(ctx, sha256, blobKey, size)
+	if err != nil {
+		return "", err
+	}
+
+	if claimed != blobKey {
+		u.deleteBlob(ctx, blobKey)
+	}
+
+	return claimed, nil
+}
+
+// releaseBlob drops a reference to a blob, deleting it from storage once nothing references it.
+func (u *uploader) releaseBlob(ctx context.Context, sha256 string) {
+	blobKey, err := u.postgres.ReleaseUploadBlob(ctx, sha256)
+	if err != nil {
+		logger.Error.Printf("Error releasing blob %s: %v", sha256, err)
+
+		return
+	}
+
+	if blobKey != "" {
+		u.deleteBlob(ctx, blobKey)
+	}
+}
+
+func (u *uploader) deleteBlob(ctx context.Context, blobKey string) {
+	if err := storage.DeleteBlob(ctx, u.store, blobKey); err != nil {
+		logger.Error.Printf("Error deleting blob %s from storage: %v", blobKey, err)
+	}
+}
@@ -0,0 +1,350 @@
+package uploader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+//nolint:revive
+const (
+	jpegTEM   = 0x01
+	jpegRST0  = 0xD0
+	jpegRST7  = 0xD7
+	jpegEOI   = 0xD9
+	jpegSOS   = 0xDA
+	jpegAPP1  = 0xE1
+	jpegAPP13 = 0xED
+
+	exifOrientationTag = 0x0112
+)
+
+var (
+	errInvalidImage = errors.New("invalid image")
+
+	exifHeader = []byte("Exif\x00\x00") //nolint:gochecknoglobals // Constant byte slice.
+
+	// PNG chunks that can hold EXIF or XMP metadata, including locations.
+	pngMetadataChunks = map[string]bool{ //nolint:gochecknoglobals // Constant lookup table.
+		"eXIf": true,
+		"tEXt": true,
+		"zTXt": true,
+		"iTXt": true,
+	}
+)
+
+// stripMetadata removes EXIF, XMP and text metadata from JPEG and PNG images as they are streamed,
+// since photos taken on phones usually include where they were taken. Only the JPEG orientation is
+// kept, so photos still display the right way up. Other files are passed through unchanged.
+func stripMetadata(reader io.Reader, mimeType string) io.ReadCloser {
+	var strip func(io.Writer, *bufio.Reader) error
+	switch mimeType {
+	case "image/jpeg":
+		strip = stripJPEG
+	case "image/png":
+		strip = stripPNG
+	default:
+		return io.NopCloser(reader)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pipeWriter.CloseWithError(strip(pipeWriter, bufio.NewReader(reader)))
+	}()
+
+	return &strippedReader{PipeReader: pipeReader, done: done}
+}
+
+// strippedReader is the output of a metadata strip running in the background. Closing it waits for
+// the strip to stop, so the source is no longer read once Close returns.
+type strippedReader struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+func (r *strippedReader) Close() error {
+	err := r.PipeReader.Close()
+	<-r.done
+
+	return err
+}
+
+// Reads the byte after a marker's 0xFF prefix, skipping any fill bytes.
+func readJPEGMarker(src *bufio.Reader) (byte, error) {
+	prefix, err := src.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if prefix != 0xFF {
+		return 0, errInvalidImage
+	}
+
+	for {
+		marker, err := src.ReadByte()
+		if err != nil || marker != 0xFF {
+			return marker, err
+		}
+	}
+}
+
+// stripJPEG copies a JPEG, dropping its APP1 segments which hold EXIF and XMP metadata and its
+// APP13 segments which hold Photoshop and IPTC metadata. Anything after the end of the primary
+// image is dropped too, such as MPF secondary images that carry their own metadata.
+func stripJPEG(dst io.Writer, src *bufio.Reader) error {
+	// Start of image.
+	if _, err := io.CopyN(dst, src, 2); err != nil {
+		return err
+	}
+
+	keptOrientation := false
+	var next byte
+	for {
+		marker := next
+		if marker == 0 {
+			var err error
+			if marker, err = readJPEGMarker(src); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+		next = 0
+
+		switch {
+		case marker == jpegEOI:
+			_, err := dst.Write([]byte{0xFF, marker})
+
+			return err
+		case marker == jpegTEM || (marker >= jpegRST0 && marker <= jpegRST7):
+			if _, err := dst.Write([]byte{0xFF, marker}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(src, length[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+		size := int64(binary.BigEndian.Uint16(length[:])) - 2
+		if size < 0 {
+			return errInvalidImage
+		}
+
+		switch marker {
+		case jpegAPP1:
+			segment := make([]byte, size)
+			if _, err := io.ReadFull(src, segment); err != nil {
+				return unexpectedEOF(err)
+			}
+
+			if orientation := exifOrientation(segment); orientation > 1 && !keptOrientation {
+				if _, err := dst.Write(orientationSegment(orientation)); err != nil {
+					return err
+				}
+				keptOrientation = true
+			}
+
+			continue
+		case jpegAPP13:
+			if _, err := src.Discard(int(size)); err != nil {
+				return unexpectedEOF(err)
+			}
+
+			continue
+		}
+
+		if _, err := dst.Write([]byte{0xFF, marker, length[0], length[1]}); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(dst, src, size); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		if marker == jpegSOS {
+			var err error
+			if next, err = copyJPEGScan(dst, src); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// copyJPEGScan copies the entropy coded data following a start of scan header, returning the
+// marker that ends it. Progressive images have several scans, each followed by more segments.
+func copyJPEGScan(dst io.Writer, src *bufio.Reader) (byte, error) {
+	for {
+		data, err := src.ReadSlice(0xFF)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			if _, err = dst.Write(data); err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		if _, err = dst.Write(data[:len(data)-1]); err != nil {
+			return 0, err
+		}
+
+		marker, err := src.ReadByte()
+		for err == nil && marker == 0xFF {
+			marker, err = src.ReadByte()
+		}
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+
+		// A zero byte escapes an 0xFF in the data, and restart markers are part of the scan.
+		if marker != 0x00 && (marker < jpegRST0 || marker > jpegRST7) {
+			return marker, nil
+		}
+		if _, err = dst.Write([]byte{0xFF, marker}); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// stripPNG copies a PNG, dropping the chunks that can hold metadata and anything after the image ends.
+func stripPNG(dst io.Writer, src *bufio.Reader) error {
+	// Signature.
+	if _, err := io.CopyN(dst, src, 8); err != nil {
+		return err
+	}
+
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(src, header[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		// Data and CRC.
+		size := int64(binary.BigEndian.Uint32(header[:4])) + 4
+		chunk := string(header[4:])
+
+		if pngMetadataChunks[chunk] {
+			if _, err := io.CopyN(io.Discard, src, size); err != nil {
+				return unexpectedEOF(err)
+			}
+
+			continue
+		}
+
+		if _, err := dst.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(dst, src, size); err != nil {
+			return unexpectedEOF(err)
+		}
+
+		if chunk == "IEND" {
+			return nil
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errInvalidImage
+	}
+
+	return err
+}
+
+// exifOrientation reads the orientation tag from an APP1 EXIF segment, returning 0 if it has none.
+func exifOrientation(segment []byte) int {
+	tiff, ok := bytes.CutPrefix(segment, exifHeader)
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 0
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	for i := range count {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+
+			return 0
+		}
+	}
+
+	return 0
+}
+
+// orientationSegment builds an APP1 segment with an EXIF block holding nothing but the orientation.
+func orientationSegment(orientation int) []byte {
+	tiff := []byte("MM\x00\x2a")
+	tiff = binary.BigEndian.AppendUint32(tiff, 8)                   // First IFD offset
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)                   // Entry count
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)  // Tag
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)                   // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)                   // Value count
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation)) //nolint:gosec // 1 to 8.
+	tiff = binary.BigEndian.AppendUint16(tiff, 0)                   // Padding
+	tiff = binary.BigEndian.AppendUint32(tiff, 0)                   // No next IFD
+
+	segment := []byte{0xFF, jpegAPP1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(2+len(exifHeader)+len(tiff))) //nolint:gosec // Fixed size.
+	segment = append(segment, exifHeader...)
+
+	return append(segment, tiff...)
+}
+
+// jpegOrientation finds the EXIF orientation of a JPEG, returning 1 if it has none.
+func jpegOrientation(data []byte) int {
+	src := bufio.NewReader(bytes.NewReader(data))
+	if _, err := src.Discard(2); err != nil {
+		return 1
+	}
+
+	for {
+		marker, err := readJPEGMarker(src)
+		if err != nil || marker == jpegSOS || marker == jpegEOI {
+			return 1
+		}
+		if marker == jpegTEM || (marker >= jpegRST0 && marker <= jpegRST7) {
+			continue
+		}
+
+		var length [2]byte
+		if _, err = io.ReadFull(src, length[:]); err != nil {
+			return 1
+		}
+		segment := make([]byte, max(int(binary.BigEndian.Uint16(length[:]))-2, 0))
+		if _, err = io.ReadFull(src, segment); err != nil {
+			return 1
+		}
+
+		if marker == jpegAPP1 {
+			if orientation := exifOrientation(segment); orientation != 0 {
+				return orientation
+			}
+		}
+	}
+}
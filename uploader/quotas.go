@@ -0,0 +1,51 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+// uploadLimit returns the largest file the user may upload and the message to respond with when a file
+// is larger. Uploads made with the shared key have no owner and only the fixed maxFileSize limit.
+func (u *uploader) uploadLimit(ctx context.Context, user *common.User) (int64, string, error) {
+	if user == nil {
+		return maxFileSize, fmt.Sprintf("File exceeds the maximum size of %d bytes", maxFileSize), nil
+	}
+
+	files, used, err := u.postgres.GetUploadUsage(ctx, user.ID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	limit, message := fileSizeLimit(common.UploadUsage{
+		UploadLimits: common.GetUploadLimits(common.PermissionLevel(user.Level)), //nolint:gosec
+		Files:        files,
+		Used:         used,
+	})
+
+	return limit, message, nil
+}
+
+// fileSizeLimit returns the largest file that fits both the per-file limit and the remaining quota.
+// Concurrent uploads can each fit the remaining quota, so it may be exceeded by a few files.
+func fileSizeLimit(usage common.UploadUsage) (int64, string) {
+	if remaining := usage.Remaining(); remaining < usage.MaxFileSize {
+		return remaining, fmt.Sprintf("File exceeds your remaining upload quota of %d bytes", remaining)
+	}
+
+	return usage.MaxFileSize, fmt.Sprintf("File exceeds the maximum size of %d bytes", usage.MaxFileSize)
+}
+
+// writeAuthError responds to requests the authenticator rejected in plain text, like the rest of the uploader.
+func writeAuthError(writer http.ResponseWriter, status int, response interface{}, _ time.Time) {
+	message := http.StatusText(status)
+	if generic, ok := response.(common.GenericResponse[string]); ok && generic.Errors != nil && len(*generic.Errors) > 0 {
+		message = (*generic.Errors)[0].Message
+	}
+
+	http.Error(writer, message, status)
+}
@@ -0,0 +1,95 @@
+package uploader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+)
+
+const (
+	sniffLength  = 512
+	maxCacheTime = 24 * time.Hour
+)
+
+var errMissingFile = errors.New("file is required")
+
+// nextFilePart skips ahead to the file field of a multipart upload without buffering earlier fields.
+func nextFilePart(request *http.Request) (*multipart.Part, error) {
+	reader, err := request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errMissingFile
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() == "file" {
+			return part, nil
+		}
+
+		if err = part.Close(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// hashingReader hashes and counts everything read through it.
+type hashingReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	size   int64
+}
+
+func newHashingReader(reader io.Reader) *hashingReader {
+	return &hashingReader{reader: reader, hash: sha256.New()}
+}
+
+func (r *hashingReader) Read(buffer []byte) (int, error) {
+	read, err := r.reader.Read(buffer)
+	r.hash.Write(buffer[:read])
+	r.size += int64(read)
+
+	return read, err
+}
+
+func (r *hashingReader) sum() string {
+	return hex.EncodeToString(r.hash.Sum(nil))
+}
+
+// memoryFile serves contents held in memory, such as uploads that are still stored in Postgres.
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error {
+	return nil
+}
+
+// setCacheHeaders lets clients cache an upload until it expires, revalidating with its ETag
+// or creation time. Uploads never change, but they can be deleted.
+func setCacheHeaders(writer http.ResponseWriter, upload *common.Upload) {
+	maxAge := maxCacheTime
+	if upload.ExpiresAt != nil {
+		maxAge = max(min(maxAge, time.Until(*upload.ExpiresAt)), 0)
+	}
+
+	writer.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	if upload.SHA256 != "" {
+		writer.Header().Set("ETag", `"`+upload.SHA256+`"`)
+	}
+}
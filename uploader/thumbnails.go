@@ -0,0 +1,349 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/db"
+	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
+	"github.com/gorilla/mux"
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultThumbnailWidth = 256
+	thumbnailQuality      = 85
+	// Keeps decoding an image from needing more than ~200MB of memory.
+	maxImagePixels = 50_000_000
+	// How many thumbnails may be generated at once, each can need up to ~200MB.
+	maxThumbnailJobs = 2
+)
+
+var (
+	errInvalidThumbnailWidth = errors.New("w must be a positive number")
+	errImageTooLarge         = errors.New("image is too large to thumbnail")
+)
+
+// hasThumbnail reports whether thumbnails can be generated for files of a MIME type.
+func hasThumbnail(mimeType string) bool {
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+// thumbnailType returns the MIME type thumbnails of a file are encoded as, photos stay JPEG
+// and everything else becomes PNG to keep transparency.
+func thumbnailType(mimeType string) string {
+	if mimeType == "image/jpeg" {
+		return "image/jpeg"
+	}
+
+	return "image/png"
+}
+
+// thumbnailWidth picks the smallest generated width that is at least the requested width.
+func thumbnailWidth(requested string) (int, error) {
+	if requested == "" {
+		return defaultThumbnailWidth, nil
+	}
+
+	width, err := strconv.Atoi(requested)
+	if err != nil || width <= 0 {
+		return 0, errInvalidThumbnailWidth
+	}
+
+	for _, size := range storage.ThumbnailWidths {
+		if size >= width {
+			return size, nil
+		}
+	}
+
+	return storage.ThumbnailWidths[len(storage.ThumbnailWidths)-1], nil
+}
+
+// imageDimensions returns the width and height an image is displayed at, or zero if it can't be read.
+func imageDimensions(data []byte, mimeType string) (int, int) {
+	if !hasThumbnail(mimeType) {
+		return 0, 0
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0
+	}
+
+	if mimeType == "image/jpeg" && jpegOrientation(data) >= 5 {
+		return config.Height, config.Width
+	}
+
+	return config.Width, config.Height
+}
+
+// decodeImage decodes a PNG, JPEG or the first frame of a GIF.
+func decodeImage(data []byte, mimeType string) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, errImageTooLarge
+	}
+
+	reader := bytes.NewReader(data)
+	switch mimeType {
+	case "image/jpeg":
+		return jpeg.Decode(reader)
+	case "image/png":
+		return png.Decode(reader)
+	case "image/gif":
+		return gif.Decode(reader)
+	default:
+		return nil, errInvalidImage
+	}
+}
+
+// makeThumbnail scales an image down to at most width pixels wide, applying its EXIF orientation.
+func makeThumbnail(data []byte, mimeType string, width int) ([]byte, error) {
+	source, err := decodeImage(data, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := 1
+	if mimeType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	bounds := source.Bounds()
+	sourceWidth, sourceHeight := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		sourceWidth, sourceHeight = sourceHeight, sourceWidth
+	}
+
+	width = min(width, sourceWidth)
+	height := max(sourceHeight*width/sourceWidth, 1)
+
+	// Scale first, so orienting has fewer pixels to move around.
+	scaledWidth, scaledHeight := width, height
+	if orientation >= 5 {
+		scaledWidth, scaledHeight = height, width
+	}
+	scaled := image.NewNRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), source, bounds, draw.Src, nil)
+
+	thumbnail := orient(scaled, orientation)
+
+	var encoded bytes.Buffer
+	if thumbnailType(mimeType) == "image/jpeg" {
+		err = jpeg.Encode(&encoded, thumbnail, &jpeg.Options{Quality: thumbnailQuality})
+	} else {
+		err = png.Encode(&encoded, thumbnail)
+	}
+
+	return encoded.Bytes(), err
+}
+
+// orient rotates and flips an image the way its EXIF orientation says it should be displayed.
+func orient(source *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return source
+	}
+
+	width, height := source.Rect.Dx(), source.Rect.Dy()
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		outWidth, outHeight = height, width
+	}
+
+	out := image.NewNRGBA(image.Rect(0, 0, outWidth, outHeight))
+	for y := range outHeight {
+		for x := range outWidth {
+			var sourceX, sourceY int
+			switch orientation {
+			case 2: // Mirrored
+				sourceX, sourceY = width-1-x, y
+			case 3: // Rotated 180°
+				sourceX, sourceY = width-1-x, height-1-y
+			case 4: // Mirrored vertically
+				sourceX, sourceY = x, height-1-y
+			case 5: // Transposed
+				sourceX, sourceY = y, x
+			case 6: // Rotated 90° clockwise
+				sourceX, sourceY = y, height-1-x
+			case 7: // Transversed
+				sourceX, sourceY = width-1-y, height-1-x
+			case 8: // Rotated 90° counterclockwise
+				sourceX, sourceY = width-1-y, x
+			}
+			out.SetNRGBA(x, y, source.NRGBAAt(sourceX, sourceY))
+		}
+	}
+
+	return out
+}
+
+// thumbnailer limits thumbnail generation to a few images at a time, since decoding needs a lot
+// of memory, and lets concurrent requests for the same thumbnail share one generation.
+type thumbnailer struct {
+	flights singleflight.Group
+	slots   chan struct{}
+}
+
+func newThumbnailer(jobs int) *thumbnailer {
+	return &thumbnailer{slots: make(chan struct{}, jobs)}
+}
+
+// storeThumbnail generates the thumbnail of a blob at the given width, loading the blob with load
+// only once a generation slot is free. The thumbnail is only kept in storage if persist is set.
+func (u *uploader) storeThumbnail(
+	ctx context.Context,
+	blobKey string,
+	mimeType string,
+	width int,
+	persist bool,
+	load func() ([]byte, error),
+) ([]byte, error) {
+	key := storage.ThumbnailKey(blobKey, width)
+
+	generated, err, _ := u.thumbnails.flights.Do(key, func() (any, error) {
+		u.thumbnails.slots <- struct{}{}
+		defer func() { <-u.thumbnails.slots }()
+
+		data, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		thumbnail, err := makeThumbnail(data, mimeType, width)
+		if err != nil {
+			logger.Warn.Printf("Failed to generate thumbnail for blob %s: %v", blobKey, err)
+
+			return nil, errInvalidImage
+		}
+
+		if !persist {
+			return thumbnail, nil
+		}
+
+		err = u.store.Put(ctx, key, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailType(mimeType))
+		if err != nil {
+			logger.Warn.Printf("Failed to store thumbnail %s: %v", key, err)
+		}
+
+		return thumbnail, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	thumbnail, _ := generated.([]byte)
+
+	return thumbnail, nil
+}
+
+// openThumbnail opens a stored thumbnail, generating it first if it doesn't exist yet. Thumbnails
+// of legacy uploads are never stored, nothing would delete them once the upload is deleted or
+// migrated to a blob under a new key.
+func (u *uploader) openThumbnail(ctx context.Context, upload *common.Upload, width int) (io.ReadSeekCloser, error) {
+	if !upload.Legacy {
+		thumbnail, err := u.store.Get(ctx, storage.ThumbnailKey(upload.BlobKey, width))
+		if !errors.Is(err, storage.ErrNotFound) {
+			return thumbnail, err
+		}
+	}
+
+	// Generation is shared with other requests, so it must not stop when this one does.
+	detached := context.WithoutCancel(ctx)
+	persist := !upload.Legacy
+	generated, err := u.storeThumbnail(detached, upload.BlobKey, upload.MimeType, width, persist, func() ([]byte, error) {
+		file, err := u.openFile(detached, upload)
+		if err != nil {
+			return nil, err
+		}
+		// Uploads were size limited when they were stored.
+		data, err := io.ReadAll(file)
+		if closeErr := file.Close(); closeErr != nil {
+			logger.Warn.Printf("Error closing upload: %v", closeErr)
+		}
+
+		return data, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return memoryFile{bytes.NewReader(generated)}, nil
+}
+
+func (u *uploader) handleThumbnail(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["key"]
+
+	width, err := thumbnailWidth(request.URL.Query().Get("w"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	upload, err := u.postgres.GetUpload(request.Context(), key)
+	if errors.Is(err, db.ErrPostgresNoRows) || (err == nil && !hasThumbnail(upload.MimeType)) {
+		http.Error(writer, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		logger.Warn.Printf("Failed to get upload: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	thumbnail, err := u.openThumbnail(request.Context(), upload, width)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, db.ErrPostgresNoRows) {
+		http.Error(writer, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	if errors.Is(err, errInvalidImage) {
+		http.Error(writer, "Thumbnail could not be generated", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	if err != nil {
+		logger.Warn.Printf("Failed to open thumbnail of %s: %v", key, err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+	defer func() {
+		if err = thumbnail.Close(); err != nil {
+			logger.Warn.Printf("Error closing thumbnail: %v", err)
+		}
+	}()
+
+	writer.Header().Set("Content-Type", thumbnailType(upload.MimeType))
+	setCacheHeaders(writer, upload)
+	if upload.SHA256 != "" {
+		writer.Header().Set("ETag", fmt.Sprintf(`"%s-w%d"`, upload.SHA256, width))
+	}
+
+	http.ServeContent(writer, request, "", upload.CreatedAt, thumbnail)
+}
@@ -2,6 +2,8 @@
 package uploader
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"crypto"
 	"encoding/json"
@@ -11,15 +13,21 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Potat-Industries/potat-api/api"
 	"github.com/Potat-Industries/potat-api/api/middleware"
 	"github.com/Potat-Industries/potat-api/common"
 	"github.com/Potat-Industries/potat-api/common/db"
 	"github.com/Potat-Industries/potat-api/common/logger"
+	"github.com/Potat-Industries/potat-api/common/storage"
 	"github.com/Potat-Industries/potat-api/common/utils"
 	"github.com/gorilla/mux"
 )
 
-const maxFileSize = 20971520 // 20MB
+const (
+	maxFileSize     = 20971520 // 20MB, for anonymous uploads
+	maxFormOverhead = 1 << 20  // 1MB for other form fields and multipart headers
+	transferTimeout = 10 * time.Minute
+)
 
 const createTable = `
 	CREATE TABLE IF NOT EXISTS file_store (
@@ -30,6 +38,23 @@ const createTable = `
 		expires_at TIMESTAMP,
 		created_at TIMESTAMP DEFAULT NOW() NOT NULL
 	);
+	ALTER TABLE file_store
+		ALTER COLUMN file DROP NOT NULL,
+		ADD COLUMN IF NOT EXISTS size BIGINT,
+		ADD COLUMN IF NOT EXISTS sha256 CHAR(64),
+		ADD COLUMN IF NOT EXISTS blob_key VARCHAR(64),
+		ADD COLUMN IF NOT EXISTS width INT,
+		ADD COLUMN IF NOT EXISTS height INT,
+		ADD COLUMN IF NOT EXISTS owner_id INT;
+	CREATE INDEX IF NOT EXISTS file_store_sha256_idx ON file_store (sha256);
+	CREATE INDEX IF NOT EXISTS file_store_owner_id_idx ON file_store (owner_id);
+	CREATE TABLE IF NOT EXISTS file_blobs (
+		sha256 CHAR(64) PRIMARY KEY,
+		blob_key VARCHAR(64) NOT NULL,
+		size BIGINT NOT NULL,
+		refs INT DEFAULT 0 NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW() NOT NULL
+	);
 `
 
 type uploader struct {
@@ -38,14 +63,20 @@ type uploader struct {
 	hasher        func(string) string
 	postgres      *db.PostgresClient
 	redis         *db.RedisClient
+	store         storage.Store
+	keys          *utils.KeyAllocator
+	thumbnails    *thumbnailer
+	publicHost    string
 	cacheDuration time.Duration
-	keyLength     int
 }
 
 type upload struct {
-	Key        string `json:"key"`
-	URL        string `json:"url"`
-	DeleteHash string `json:"delete_hash"`
+	Key          string `json:"key"`
+	URL          string `json:"url"`
+	DeleteHash   string `json:"delete_hash"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
 }
 
 func getHashGenerator(secret string) func(key string) string {
@@ -69,43 +100,60 @@ func StartServing(
 		logger.Error.Fatal("Config: Uploader host and port must be set")
 	}
 
+	store, err := storage.New(config.Storage)
+	if err != nil {
+		return fmt.Errorf("failed initializing upload storage: %w", err)
+	}
+
 	uploader := &uploader{
-		keyLength:     6,
 		cacheDuration: 30 * time.Minute,
 		hasher:        getHashGenerator(config.Uploader.AuthKey),
+		publicHost:    config.Uploader.PublicHost,
 		postgres:      postgres,
 		redis:         redis,
+		store:         store,
+		thumbnails:    newThumbnailer(maxThumbnailJobs),
 	}
 
 	router := mux.NewRouter()
 
 	router.Use(middleware.LogRequest(metrics))
-	router.Use(middleware.NewRateLimiter(200, 1*time.Minute, redis))
+	limiter := middleware.NewRateLimiter("uploader", config.RateLimits, redis)
+	router.Use(limiter.Policy("default", common.RateLimitPolicy{Limit: 200, Window: 60}))
 	router.HandleFunc("/{key}", uploader.handleGet).Methods(http.MethodGet)
+	router.HandleFunc("/{key}/qr", uploader.handleQR).Methods(http.MethodGet)
+	router.HandleFunc("/{key}/thumb", uploader.handleThumbnail).Methods(http.MethodGet)
 
 	deleteRouter := router.PathPrefix("/delete").Subrouter()
-	deleteRouter.Use(middleware.NewRateLimiter(15, 1*time.Minute, redis))
+	deleteRouter.Use(limiter.Policy("delete", common.RateLimitPolicy{Limit: 15, Window: 60}))
 	deleteRouter.HandleFunc("/{key}/{hash}", uploader.handleDelete).Methods(http.MethodGet)
 
 	authedRoute := router.PathPrefix("/").Subrouter()
 	authedRoute.HandleFunc("/upload", uploader.handleUpload).Methods(http.MethodPost)
 
-	authenicator := middleware.NewAuthenticator(config.Uploader.AuthKey, nil)
-	authedRoute.Use(authenicator.SetStaticAuthMiddleware())
-	authedRoute.Use(middleware.NewRateLimiter(25, 1*time.Minute, redis))
+	// The shared key uploads anonymously, users upload with their own API keys.
+	sharedKey := middleware.NewAuthenticator(config.Uploader.AuthKey, writeAuthError)
+	authenicator := middleware.NewAuthenticator(config.Twitch.ClientSecret, writeAuthError)
+	authedRoute.Use(middleware.InjectDatabases(postgres, redis, nil))
+	authedRoute.Use(authenicator.SetStaticOrDynamicAuthMiddleware(sharedKey))
+	authedRoute.Use(limiter.Policy("upload", common.RateLimitPolicy{Limit: 25, Window: 60}))
 
+	// There is no total read timeout, uploads and downloads extend their own deadlines
+	// as they can take far longer than any other request.
 	uploader.server = &http.Server{
-		Handler:      router,
-		Addr:         config.Uploader.Host + ":" + config.Uploader.Port,
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Handler:           router,
+		Addr:              config.Uploader.Host + ":" + config.Uploader.Port,
+		WriteTimeout:      15 * time.Second,
+		ReadHeaderTimeout: 15 * time.Second,
+		IdleTimeout:       60 * time.Second,
 	}
 	uploader.router = router
 
+	keyLength := 6
 	if config.Haste.KeyLength != 0 {
-		uploader.keyLength = config.Haste.KeyLength
+		keyLength = config.Haste.KeyLength
 	}
+	uploader.keys = utils.NewKeyAllocator(utils.AlphabetByName(config.Haste.KeyAlphabet), keyLength, keyLength+6, 8)
 
 	uploader.postgres.CheckTableExists(ctx, createTable)
 
@@ -121,16 +169,56 @@ func (u *uploader) setRedis(ctx context.Context, key string, data []byte) {
 	}
 }
 
+// extendDeadlines gives a request transferTimeout to stream its body or response, instead
+// of the server's default timeouts.
+func extendDeadlines(writer http.ResponseWriter, read bool) {
+	controller := http.NewResponseController(writer)
+	deadline := time.Now().Add(transferTimeout)
+
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		logger.Warn.Printf("Failed to extend write deadline: %v", err)
+	}
+
+	if !read {
+		return
+	}
+
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		logger.Warn.Printf("Failed to extend read deadline: %v", err)
+	}
+}
+
 func (u *uploader) handleUpload(writer http.ResponseWriter, request *http.Request) {
-	err := request.ParseMultipartForm(maxFileSize)
+	extendDeadlines(writer, true)
+
+	var ownerID *int
+	user, _ := request.Context().Value(middleware.AuthedUser).(*common.User)
+	if user != nil {
+		if !middleware.HasScope(request.Context(), api.ScopeUploadsWrite) {
+			http.Error(writer, "Missing required scope: "+api.ScopeUploadsWrite, http.StatusForbidden)
+
+			return
+		}
+		ownerID = &user.ID
+	}
+
+	limit, limitMessage, err := u.uploadLimit(request.Context(), user)
 	if err != nil {
-		logger.Error.Printf("Error parsing form: %v", err)
-		http.Error(writer, "Failed to parse form", http.StatusBadRequest)
+		logger.Error.Printf("Error getting upload usage: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
 
 		return
 	}
 
-	file, header, err := request.FormFile("file")
+	if limit <= 0 {
+		http.Error(writer, "Upload quota exceeded", http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, limit+maxFormOverhead)
+
+	part, err := nextFilePart(request)
 	if err != nil {
 		logger.Error.Printf("Error retrieving file: %v", err)
 		http.Error(writer, "File is required", http.StatusBadRequest)
@@ -138,68 +226,171 @@ func (u *uploader) handleUpload(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 	defer func() {
-		if err = file.Close(); err != nil {
+		if err = part.Close(); err != nil {
 			logger.Error.Printf("Error closing file: %v", err)
 		}
 	}()
 
-	fileName := header.Filename
-	fileData, err := io.ReadAll(file)
+	fileName := part.FileName()
+	file := bufio.NewReaderSize(http.MaxBytesReader(writer, part, limit), sniffLength)
+
+	// Only the start of the file is needed to detect its type, the rest is streamed to storage.
+	head, err := file.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		writeStoreError(writer, err, limitMessage)
+
+		return
+	}
+	mimeType := http.DetectContentType(head)
+
+	blobKey, err := newBlobKey()
 	if err != nil {
-		logger.Error.Printf("Error reading file: %v", err)
-		http.Error(writer, "Failed to read file", http.StatusInternalServerError)
+		logger.Error.Printf("Error generating blob key: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
 
 		return
 	}
 
-	mimeType := http.DetectContentType(fileData)
+	stripped := stripMetadata(file, mimeType)
+	defer stripped.Close() //nolint:errcheck
+
+	// Images are kept in memory as well to read their dimensions and generate a thumbnail.
+	cleanupCtx := context.WithoutCancel(request.Context())
+	hashed := newHashingReader(stripped)
+	var imageData bytes.Buffer
+	var contents io.Reader = hashed
+	if hasThumbnail(mimeType) {
+		contents = io.TeeReader(hashed, &imageData)
+	}
 
-	key, err := utils.RandomString(u.keyLength)
+	if err = u.store.Put(request.Context(), blobKey, contents, -1, mimeType); err != nil {
+		u.deleteBlob(cleanupCtx, blobKey)
+		writeStoreError(writer, err, limitMessage)
+
+		return
+	}
+	sum := hashed.sum()
+
+	// A user uploading the same file again gets back the upload that already has it. Anonymous
+	// uploads share one identity, returning an existing one would hand out its delete hash, so
+	// they always get a new key that shares the stored blob instead.
+	if ownerID != nil {
+		existing, err := u.postgres.FindUploadBySHA256(request.Context(), sum, *ownerID)
+		if err == nil {
+			u.deleteBlob(cleanupCtx, blobKey)
+			u.writeUpload(writer, request, existing, http.StatusOK)
+
+			return
+		}
+		if !errors.Is(err, db.ErrPostgresNoRows) {
+			logger.Warn.Printf("Error finding duplicate upload: %v", err)
+		}
+	}
+
+	claimed, err := u.claimBlob(request.Context(), sum, blobKey, hashed.size)
 	if err != nil {
-		logger.Error.Printf("Error generating key: %v", err)
+		logger.Error.Printf("Error claiming blob: %v", err)
+		u.deleteBlob(cleanupCtx, blobKey)
 		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
 
 		return
 	}
 
-	ok, createdAt := u.postgres.NewUpload(
-		request.Context(),
-		key,
-		fileData,
-		mimeType,
-		fileName,
-	)
-	if !ok {
+	created := common.Upload{
+		OwnerID:  ownerID,
+		MimeType: mimeType,
+		SHA256:   sum,
+		Size:     hashed.size,
+		BlobKey:  claimed,
+	}
+	created.Width, created.Height = imageDimensions(imageData.Bytes(), mimeType)
+	if fileName != "" {
+		created.FileName = &fileName
+	}
+
+	_, err = u.keys.Allocate(request.Context(), func(ctx context.Context, key string) (bool, error) {
+		created.Key = key
+		createdAt, err := u.postgres.NewUpload(ctx, &created)
+		if errors.Is(err, db.ErrPostgresNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		created.CreatedAt = *createdAt
+
+		return true, nil
+	})
+	if err != nil {
 		logger.Error.Printf("Error inserting upload: %v", err)
+		u.releaseBlob(cleanupCtx, sum)
 		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
 
 		return
 	}
 
-	parentCtx := context.WithoutCancel(request.Context())
-	go u.setRedis(parentCtx, key, fileData)
+	// Blobs shared with an earlier upload already have their thumbnail.
+	if claimed == blobKey && hasThumbnail(mimeType) {
+		go func() {
+			_, _ = u.storeThumbnail(cleanupCtx, claimed, mimeType, defaultThumbnailWidth, true, func() ([]byte, error) {
+				return imageData.Bytes(), nil
+			})
+		}()
+	}
+
+	u.writeUpload(writer, request, &created, http.StatusCreated)
+}
 
-	response := fmt.Sprintf("https://%s/%s", request.Host, key)
+func (u *uploader) writeUpload(
+	writer http.ResponseWriter,
+	request *http.Request,
+	stored *common.Upload,
+	status int,
+) {
 	writer.Header().Set("Content-Type", "application/json")
-	writer.WriteHeader(http.StatusCreated)
+	writer.WriteHeader(status)
+
+	response := upload{
+		Key:        stored.Key,
+		URL:        fmt.Sprintf("https://%s/%s", request.Host, stored.Key),
+		DeleteHash: u.hasher(stored.Key + stored.CreatedAt.String()),
+		Width:      stored.Width,
+		Height:     stored.Height,
+	}
+	if hasThumbnail(stored.MimeType) {
+		response.ThumbnailURL = response.URL + "/thumb"
+	}
 
-	err = json.NewEncoder(writer).Encode(upload{
-		Key:        key,
-		URL:        response,
-		DeleteHash: u.hasher(key + createdAt.String()),
-	})
-	if err != nil {
+	if err := json.NewEncoder(writer).Encode(response); err != nil {
 		logger.Error.Printf("Error encoding response: %v", err)
 	}
 }
 
+// writeStoreError responds to an upload that couldn't be read or stored, with limitMessage if it
+// was too large.
+func writeStoreError(writer http.ResponseWriter, err error, limitMessage string) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		http.Error(writer, limitMessage, http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	if errors.Is(err, errInvalidImage) {
+		http.Error(writer, "File is not a valid image", http.StatusBadRequest)
+
+		return
+	}
+
+	logger.Error.Printf("Error storing upload: %v", err)
+	http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+}
+
 func (u *uploader) handleDelete(writer http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	key := vars["key"]
 	hash := vars["hash"]
 
-	u.redis.Del(request.Context(), key)
-
 	createdAt, err := u.postgres.GetUploadCreatedAt(request.Context(), key)
 	if err != nil {
 		http.Error(writer, "Not Found", http.StatusNotFound)
@@ -213,35 +404,111 @@ func (u *uploader) handleDelete(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
-	ok := u.postgres.DeleteFileByKey(request.Context(), key)
-	if !ok {
+	blobKey, err := u.postgres.DeleteUpload(request.Context(), key)
+	if errors.Is(err, db.ErrPostgresNoRows) {
 		http.Error(writer, "Not Found", http.StatusNotFound)
 
 		return
 	}
 
+	if err != nil {
+		logger.Error.Printf("Error deleting upload %s: %v", key, err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	// Other uploads of the same file keep the blob alive.
+	if blobKey != "" {
+		u.deleteBlob(request.Context(), blobKey)
+	}
+
 	writer.WriteHeader(http.StatusNoContent)
 }
 
 func (u *uploader) handleGet(writer http.ResponseWriter, request *http.Request) {
+	extendDeadlines(writer, false)
+
 	vars := mux.Vars(request)
 	key := vars["key"]
 
-	cache, err := u.redis.Get(request.Context(), key).Bytes()
-	if cache != nil && err == nil {
-		contentType := http.DetectContentType(cache)
-		writer.Header().Set("Content-Type", contentType)
-		writer.Header().Set("X-Cache-Hit", "HIT")
-		writer.WriteHeader(http.StatusOK)
-		_, err = writer.Write(cache)
-		if err != nil {
-			logger.Warn.Printf("Failed to write document: %v", err)
+	upload, err := u.postgres.GetUpload(request.Context(), key)
+	if errors.Is(err, db.ErrPostgresNoRows) {
+		http.Error(writer, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		logger.Warn.Printf("Failed to get upload: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	file, err := u.openFile(request.Context(), upload)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, db.ErrPostgresNoRows) {
+		http.Error(writer, "Not Found", http.StatusNotFound)
+
+		return
+	}
+
+	if err != nil {
+		logger.Warn.Printf("Failed to open upload %s: %v", key, err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+	defer func() {
+		if err = file.Close(); err != nil {
+			logger.Warn.Printf("Error closing upload: %v", err)
 		}
+	}()
+
+	if upload.FileName != nil {
+		writer.Header().Set("Content-Disposition", "inline; filename=\""+*upload.FileName+"\"")
+	}
+	writer.Header().Set("Content-Type", upload.MimeType)
+	setCacheHeaders(writer, upload)
+
+	// Handles Range, If-None-Match, If-Modified-Since and friends.
+	http.ServeContent(writer, request, "", upload.CreatedAt, file)
+}
+
+// openFile opens the contents of an upload, which are still in Postgres for legacy uploads
+// that haven't been migrated to blob storage yet.
+func (u *uploader) openFile(ctx context.Context, upload *common.Upload) (io.ReadSeekCloser, error) {
+	if !upload.Legacy {
+		return u.store.Get(ctx, upload.BlobKey)
+	}
+
+	data, err := u.postgres.GetLegacyUploadFile(ctx, upload.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return memoryFile{bytes.NewReader(data)}, nil
+}
+
+func (u *uploader) handleQR(writer http.ResponseWriter, request *http.Request) {
+	key := mux.Vars(request)["key"]
+
+	options, err := utils.ParseQROptions(request.URL.Query())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
 
 		return
 	}
 
-	data, mimeType, name, _, err := u.postgres.GetFileByKey(request.Context(), key)
+	host := u.publicHost
+	if host == "" {
+		host = request.Host
+	}
+	content := fmt.Sprintf("https://%s/%s", host, key)
+	cacheKey := options.CacheKey(content)
+
+	// Check the upload still exists before serving a cached code for it.
+	_, err = u.postgres.GetUploadCreatedAt(request.Context(), key)
 	if errors.Is(err, db.ErrPostgresNoRows) {
 		http.Error(writer, "Not Found", http.StatusNotFound)
 
@@ -249,22 +516,37 @@ func (u *uploader) handleGet(writer http.ResponseWriter, request *http.Request)
 	}
 
 	if err != nil {
-		logger.Warn.Printf("Failed to get document: %v", err)
+		logger.Warn.Printf("Failed to get upload: %v", err)
 		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
 
 		return
 	}
 
-	parentCtx := context.WithoutCancel(request.Context())
-	go u.setRedis(parentCtx, key, data)
+	cache, err := u.redis.Get(request.Context(), cacheKey).Bytes()
+	if cache != nil && err == nil {
+		writer.Header().Set("Content-Type", options.ContentType())
+		writer.Header().Set("X-Cache-Hit", "HIT")
+		if _, err = writer.Write(cache); err != nil {
+			logger.Warn.Printf("Failed to write QR code: %v", err)
+		}
 
-	if name != nil {
-		writer.Header().Set("Content-Disposition", "inline; filename=\""+*name+"\"")
+		return
 	}
-	writer.Header().Set("Content-Type", mimeType)
-	_, err = writer.Write(data)
+
+	image, err := utils.RenderQR(content, options)
 	if err != nil {
-		logger.Error.Printf("Error writing file: %v", err)
-		http.Error(writer, "Failed to write file", http.StatusInternalServerError)
+		logger.Error.Printf("Error rendering QR code: %v", err)
+		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
+
+		return
+	}
+
+	parentCtx := context.WithoutCancel(request.Context())
+	go u.setRedis(parentCtx, cacheKey, image)
+
+	writer.Header().Set("Content-Type", options.ContentType())
+	writer.Header().Set("X-Cache-Hit", "MISS")
+	if _, err = writer.Write(image); err != nil {
+		logger.Warn.Printf("Failed to write QR code: %v", err)
 	}
 }
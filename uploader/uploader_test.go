@@ -0,0 +1,286 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Potat-Industries/potat-api/common"
+	"github.com/Potat-Industries/potat-api/common/storage"
+)
+
+func TestUploader__StreamsFilePart(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.WriteField("comment", "skipped")
+	file, _ := form.CreateFormFile("file", "potato.txt")
+	_, _ = file.Write([]byte("hello potato"))
+	_ = form.Close()
+
+	request := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+
+	part, err := nextFilePart(request)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if part.FileName() != "potato.txt" {
+		t.Errorf("Expected file name potato.txt, got %q", part.FileName())
+	}
+
+	hashed := newHashingReader(part)
+	if _, err = io.Copy(io.Discard, hashed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// sha256("hello potato")
+	expected := "0ed6aa2fba0754bf93f72f7c77460389beed5ac20bd31e7f040641bc0eeda499"
+	if hashed.size != 12 {
+		t.Errorf("Expected size 12, got %d", hashed.size)
+	}
+	if hashed.sum() != expected {
+		t.Errorf("Expected hash %s, got %s", expected, hashed.sum())
+	}
+}
+
+func TestUploader__StripsJPEGMetadata(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	xmp := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), "<gps>secret location</gps>"...)
+	xmpSegment := binary.BigEndian.AppendUint16([]byte{0xFF, jpegAPP1}, uint16(len(xmp)+2))
+	xmpSegment = append(xmpSegment, xmp...)
+
+	var photo bytes.Buffer
+	photo.Write(encoded.Bytes()[:2])
+	photo.Write(orientationSegment(6))
+	photo.Write(xmpSegment)
+	photo.Write(encoded.Bytes()[2:])
+
+	stripped, err := io.ReadAll(stripMetadata(&photo, "image/jpeg"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bytes.Contains(stripped, []byte("secret location")) {
+		t.Error("Expected XMP metadata to be stripped")
+	}
+	if orientation := jpegOrientation(stripped); orientation != 6 {
+		t.Errorf("Expected orientation 6 to be kept, got %d", orientation)
+	}
+	if width, height := imageDimensions(stripped, "image/jpeg"); width != 2 || height != 4 {
+		t.Errorf("Expected rotated dimensions 2x4, got %dx%d", width, height)
+	}
+}
+
+func TestUploader__StripsJPEGTrailingDataAndIPTC(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	iptc := []byte("Photoshop 3.0\x008BIM\x04\x04secret city")
+	iptcSegment := binary.BigEndian.AppendUint16([]byte{0xFF, jpegAPP13}, uint16(len(iptc)+2))
+	iptcSegment = append(iptcSegment, iptc...)
+
+	// A secondary image after the primary one, as in MPF files, with its own EXIF location.
+	exif := append([]byte("Exif\x00\x00"), "secret gps"...)
+	secondary := []byte{0xFF, 0xD8}
+	secondary = binary.BigEndian.AppendUint16(append(secondary, 0xFF, jpegAPP1), uint16(len(exif)+2))
+	secondary = append(secondary, exif...)
+	secondary = append(secondary, encoded.Bytes()[2:]...)
+
+	var photo bytes.Buffer
+	photo.Write(encoded.Bytes()[:2])
+	photo.Write(iptcSegment)
+	photo.Write(encoded.Bytes()[2:])
+	photo.Write(secondary)
+
+	stripped, err := io.ReadAll(stripMetadata(&photo, "image/jpeg"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if bytes.Contains(stripped, []byte("secret city")) {
+		t.Error("Expected APP13 metadata to be stripped")
+	}
+	if bytes.Contains(stripped, []byte("secret gps")) {
+		t.Error("Expected data after the primary image to be dropped")
+	}
+	if !bytes.Equal(stripped, encoded.Bytes()) {
+		t.Errorf("Expected only the primary image to remain, got %d bytes instead of %d", len(stripped), encoded.Len())
+	}
+	if _, err = jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("Expected stripped image to decode, got %v", err)
+	}
+}
+
+// gatedReader blocks its second read until released, like a request body waiting on the client.
+type gatedReader struct {
+	source  io.Reader
+	blocked chan struct{}
+	release chan struct{}
+	reads   int
+}
+
+func (r *gatedReader) Read(buffer []byte) (int, error) {
+	r.reads++
+	if r.reads == 2 {
+		close(r.blocked)
+		<-r.release
+	}
+
+	return r.source.Read(buffer)
+}
+
+func TestUploader__StripStopsReadingOnClose(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 512, 512))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 31)
+	}
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	source := &gatedReader{
+		source:  bytes.NewReader(encoded.Bytes()),
+		blocked: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	stripped := stripMetadata(source, "image/jpeg")
+	go io.Copy(io.Discard, stripped) //nolint:errcheck
+	<-source.blocked
+
+	// Storing the upload failed while the strip is still reading the request body.
+	closed := make(chan error)
+	go func() { closed <- stripped.Close() }()
+
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the strip to stop reading")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(source.release)
+	if err := <-closed; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The request body is closed after the handler returns, the strip must not read it anymore.
+	reads := source.reads
+	if _, err := io.Copy(io.Discard, source.source); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if source.reads != reads {
+		t.Errorf("Expected no reads after close, got %d more", source.reads-reads)
+	}
+}
+
+func TestUploader__Thumbnail(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewNRGBA(image.Rect(0, 0, 300, 150))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	width, err := thumbnailWidth("100")
+	if err != nil || width != 128 {
+		t.Fatalf("Expected width 128, got %d (%v)", width, err)
+	}
+
+	thumbnail, err := makeThumbnail(encoded.Bytes(), "image/png", width)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(thumbnail))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Width != 128 || config.Height != 64 {
+		t.Errorf("Expected a 128x64 thumbnail, got %dx%d", config.Width, config.Height)
+	}
+}
+
+func TestUploader__ThumbnailGenerationIsShared(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u := &uploader{store: store, thumbnails: newThumbnailer(1)}
+
+	var encoded bytes.Buffer
+	if err = png.Encode(&encoded, image.NewNRGBA(image.Rect(0, 0, 300, 150))); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() ([]byte, error) {
+		loads.Add(1)
+		<-release
+
+		return encoded.Bytes(), nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := u.storeThumbnail(context.Background(), "blob", "image/png", 128, true, load); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+
+	// Let every request join the generation before it finishes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := loads.Load(); got != 1 {
+		t.Errorf("Expected concurrent requests to share one generation, got %d", got)
+	}
+	if len(u.thumbnails.slots) != 0 {
+		t.Error("Expected the generation slot to be released")
+	}
+}
+
+func TestUploader__FileSizeLimit(t *testing.T) {
+	limits := common.GetUploadLimits(common.USER)
+
+	tests := []struct {
+		name     string
+		used     int64
+		expected int64
+	}{
+		{"per-file limit", 0, limits.MaxFileSize},
+		{"remaining quota", limits.Quota - 1024, 1024},
+		{"quota used up", limits.Quota + 1024, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, _ := fileSizeLimit(common.UploadUsage{UploadLimits: limits, Used: tc.used})
+			if limit != tc.expected {
+				t.Errorf("Expected limit %d, got %d", tc.expected, limit)
+			}
+		})
+	}
+}